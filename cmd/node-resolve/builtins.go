@@ -0,0 +1,78 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// builtinModules are the top-level names of the Node.js builtin modules, as in
+// src/builtins.ts.
+var builtinModules = []string{
+	"_http_agent",
+	"_http_client",
+	"_http_common",
+	"_http_incoming",
+	"_http_outgoing",
+	"_http_server",
+	"_stream_duplex",
+	"_stream_passthrough",
+	"_stream_readable",
+	"_stream_transform",
+	"_stream_wrap",
+	"_stream_writable",
+	"_tls_common",
+	"_tls_wrap",
+	"assert",
+	"async_hooks",
+	"buffer",
+	"child_process",
+	"cluster",
+	"console",
+	"constants",
+	"crypto",
+	"dgram",
+	"diagnostics_channel",
+	"dns",
+	"domain",
+	"events",
+	"fs",
+	"http",
+	"http2",
+	"https",
+	"inspector",
+	"module",
+	"net",
+	"os",
+	"path",
+	"perf_hooks",
+	"process",
+	"punycode",
+	"querystring",
+	"readline",
+	"repl",
+	"stream",
+	"string_decoder",
+	"sys",
+	"timers",
+	"tls",
+	"trace_events",
+	"tty",
+	"url",
+	"util",
+	"v8",
+	"vm",
+	"wasi",
+	"worker_threads",
+	"zlib",
+}
+
+// isCoreModule reports whether id names a builtin module, with or without the
+// node: prefix; subpaths such as stream/promises belong to their top-level module.
+func isCoreModule(id string) bool {
+	if strings.HasPrefix(id, "node:") {
+		return true
+	}
+	name, _, _ := strings.Cut(id, "/")
+	_, found := slices.BinarySearch(builtinModules, name)
+	return found
+}
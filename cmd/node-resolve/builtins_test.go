@@ -0,0 +1,30 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIsCoreModule(t *testing.T) {
+	if !slices.IsSorted(builtinModules) {
+		t.Fatal("builtinModules is not sorted")
+	}
+
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"fs", true},
+		{"node:test", true},
+		{"stream/promises", true},
+		{"_http_agent", true},
+		{"fsevents", false},
+		{"@scope/fs", false},
+		{"./fs", false},
+	}
+	for _, tt := range tests {
+		if got := isCoreModule(tt.id); got != tt.want {
+			t.Errorf("isCoreModule(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
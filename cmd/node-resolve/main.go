@@ -0,0 +1,233 @@
+// Command node-resolve resolves module specifiers with the Node.js algorithm.
+//
+//	node-resolve [flags] specifier...
+//	node-resolve [flags] -serve
+//
+// Each resolved path is printed on its own line. With -json, a JSON array holds
+// the result or failure reason of every specifier. The exit code is 1 if any
+// specifier fails to resolve and 2 on usage errors.
+//
+// With -serve, line-delimited JSON-RPC requests are read from stdin and answered
+// on stdout by a resolver that keeps its cache between requests; see serve.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	resolve "github.com/startracex/node-resolve"
+)
+
+// listFlag is a comma-separated list flag that may be repeated.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// mapFlag is a repeated key=value,value flag.
+type mapFlag map[string][]string
+
+func (m mapFlag) String() string {
+	var pairs []string
+	for key, values := range m {
+		pairs = append(pairs, key+"="+strings.Join(values, ","))
+	}
+	return strings.Join(pairs, " ")
+}
+
+func (m mapFlag) Set(value string) error {
+	key, values, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value,value, got %q", value)
+	}
+	var list listFlag
+	list.Set(values)
+	m[key] = append(m[key], list...)
+	return nil
+}
+
+// config mirrors resolve.ResolverConfig with the option names of the npm package.
+type config struct {
+	Extensions            []string            `json:"extensions"`
+	ExtensionMap          map[string][]string `json:"extensionMap"`
+	ExtensionMapFirst     bool                `json:"extensionMapFirst"`
+	Conditions            []string            `json:"conditions"`
+	MainFields            []string            `json:"mainFields"`
+	MainFieldConditions   map[string][]string `json:"mainFieldConditions"`
+	IndexName             string              `json:"indexName"`
+	IndexNames            []string            `json:"indexNames"`
+	DisableDirectoryIndex bool                `json:"disableDirectoryIndex"`
+	ModulesDirectoryName  string              `json:"modulesDirectoryName"`
+	ManifestFileName      string              `json:"manifestFileName"`
+	ProbeFallbacks        bool                `json:"probeFallbacks"`
+	AdditionalModulePaths []string            `json:"additionalModulePaths"`
+}
+
+// normalize adds the "default" condition, which is always active, when it is
+// missing from Conditions.
+func (c *config) normalize() {
+	if !slices.Contains(c.Conditions, "default") {
+		c.Conditions = append(slices.Clip(c.Conditions), "default")
+	}
+}
+
+func (c config) newResolver() *resolve.ModuleResolver {
+	return resolve.NewModuleResolver(&resolve.ResolverConfig{
+		Extensions:            c.Extensions,
+		ExtensionMap:          c.ExtensionMap,
+		ExtensionMapFirst:     c.ExtensionMapFirst,
+		Conditions:            c.Conditions,
+		MainFields:            c.MainFields,
+		MainFieldConditions:   c.MainFieldConditions,
+		IndexName:             c.IndexName,
+		IndexNames:            c.IndexNames,
+		DisableDirectoryIndex: c.DisableDirectoryIndex,
+		ModulesDirectoryName:  c.ModulesDirectoryName,
+		ManifestFileName:      c.ManifestFileName,
+		ProbeFallbacks:        c.ProbeFallbacks,
+		AdditionalModulePaths: c.AdditionalModulePaths,
+		IsCoreModule:          isCoreModule,
+	})
+}
+
+type options struct {
+	from   string
+	json   bool
+	serve  bool
+	config config
+}
+
+func newFlagSet(stderr io.Writer) (*flag.FlagSet, *options) {
+	o := &options{}
+	o.config.ExtensionMap = make(map[string][]string)
+	o.config.MainFieldConditions = make(map[string][]string)
+
+	flags := flag.NewFlagSet("node-resolve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: node-resolve [flags] specifier...")
+		fmt.Fprintln(stderr, "       node-resolve [flags] -serve")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&o.from, "from", "", "directory to resolve from (default current directory)")
+	flags.BoolVar(&o.json, "json", false, "print results as JSON, including failure reasons")
+	flags.BoolVar(&o.serve, "serve", false, "answer line-delimited JSON-RPC requests on stdin")
+	flags.Var((*listFlag)(&o.config.Extensions), "extensions", "file extensions to try, e.g. .js,.ts")
+	flags.Var(mapFlag(o.config.ExtensionMap), "extension-map", "extension substitutions, e.g. .js=.ts,.js (repeatable)")
+	flags.BoolVar(&o.config.ExtensionMapFirst, "extension-map-first", false, "try extension substitutions before the exact path")
+	flags.Var((*listFlag)(&o.config.Conditions), "conditions", "active exports and imports conditions, e.g. browser,import")
+	flags.Var((*listFlag)(&o.config.MainFields), "main-fields", "manifest fields naming the main file (default main)")
+	flags.Var(mapFlag(o.config.MainFieldConditions), "main-field-conditions", "conditions enabling a main field, e.g. module=import (repeatable)")
+	flags.StringVar(&o.config.IndexName, "index-name", "", "directory index file name (default index)")
+	flags.Var((*listFlag)(&o.config.IndexNames), "index-names", "directory index file names tried in order")
+	flags.BoolVar(&o.config.DisableDirectoryIndex, "disable-directory-index", false, "do not resolve directories to their index")
+	flags.StringVar(&o.config.ModulesDirectoryName, "modules-dir", "", "modules directory name (default node_modules)")
+	flags.StringVar(&o.config.ManifestFileName, "manifest", "", "manifest file name (default package.json)")
+	flags.BoolVar(&o.config.ProbeFallbacks, "probe-fallbacks", false, "try every target of array fallbacks on disk")
+	flags.Var((*listFlag)(&o.config.AdditionalModulePaths), "paths", "directories searched after node_modules, like NODE_PATH")
+	return flags, o
+}
+
+type result struct {
+	Specifier string       `json:"specifier"`
+	Base      string       `json:"base"`
+	Resolved  string       `json:"resolved,omitempty"`
+	Error     *resultError `json:"error,omitempty"`
+}
+
+type resultError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func newResultError(err error) *resultError {
+	e := &resultError{Code: "ERR_INTERNAL", Message: err.Error()}
+	var resolveErr *resolve.ResolveError
+	if errors.As(err, &resolveErr) {
+		e.Code = resolveErr.Code
+	}
+	return e
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags, o := newFlagSet(stderr)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() == 0 && !o.serve {
+		flags.Usage()
+		return 2
+	}
+
+	o.config.normalize()
+
+	base, err := filepath.Abs(o.from)
+	if err != nil {
+		fmt.Fprintln(stderr, "node-resolve:", err)
+		return 2
+	}
+	if o.serve {
+		if err := newServer(base, o.config).serve(stdin, stdout); err != nil {
+			fmt.Fprintln(stderr, "node-resolve:", err)
+			return 1
+		}
+		return 0
+	}
+	resolver := o.config.newResolver()
+
+	code := 0
+	results := make([]result, 0, flags.NArg())
+	for _, specifier := range flags.Args() {
+		res := result{Specifier: specifier, Base: base}
+		resolved, err := resolver.TryResolve(specifier, base)
+		if err != nil {
+			code = 1
+			res.Error = newResultError(err)
+		}
+		res.Resolved = resolved
+		results = append(results, res)
+
+		if o.json {
+			continue
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+		} else {
+			fmt.Fprintln(stdout, resolved)
+		}
+	}
+
+	if o.json {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			fmt.Fprintln(stderr, "node-resolve:", err)
+			return 1
+		}
+	}
+	return code
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
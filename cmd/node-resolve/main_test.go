@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestRun(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"app/src/main.ts":                   "",
+		"app/node_modules/pkg/package.json": `{"exports": {".": {"browser": "./browser.js", "default": "./index.js"}}}`,
+		"app/node_modules/pkg/browser.js":   "",
+		"app/node_modules/pkg/index.js":     "",
+	})
+	app := filepath.Join(root, "app")
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout []string
+	}{
+		{"default", []string{"pkg"}, 0, []string{"node_modules/pkg/index.js"}},
+		{"conditions", []string{"-conditions", "browser,import", "pkg"}, 0, []string{"node_modules/pkg/browser.js"}},
+		{"extension map", []string{"-extensions", ".js", "-extension-map", ".js=.ts", "./src/main.js"}, 0, []string{"src/main.ts"}},
+		{"multiple", []string{"pkg", "node:fs"}, 0, []string{"node_modules/pkg/index.js", "node:fs"}},
+		{"builtins", []string{"fs", "stream/promises"}, 0, []string{"fs", "stream/promises"}},
+		{"failure", []string{"pkg", "nope"}, 1, []string{"node_modules/pkg/index.js"}},
+		{"no specifiers", nil, 2, nil},
+		{"bad flag", []string{"-extension-map", ".js", "pkg"}, 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"-from", app}, tt.args...), nil, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("run(%q) = %d, want %d; stderr: %s", tt.args, code, tt.wantCode, stderr.String())
+			}
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+				if line == "" {
+					continue
+				}
+				if rel, err := filepath.Rel(app, line); err == nil && filepath.IsAbs(line) {
+					line = filepath.ToSlash(rel)
+				}
+				got = append(got, line)
+			}
+			if !reflect.DeepEqual(got, tt.wantStdout) {
+				t.Errorf("run(%q) stdout = %q, want %q", tt.args, got, tt.wantStdout)
+			}
+		})
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"node_modules/pkg/package.json": `{"exports": {".": "./index.js"}}`,
+		"node_modules/pkg/index.js":     "",
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-from", root, "-json", "pkg", "pkg/internal"}, nil, &stdout, &stderr)
+	if code != 1 {
+		t.Errorf("run() = %d, want 1", code)
+	}
+
+	var got []result
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, stdout.String())
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d results, want 2", len(got))
+	}
+	if want := filepath.Join(root, "node_modules", "pkg", "index.js"); got[0].Resolved != want || got[0].Error != nil {
+		t.Errorf("results[0] = %+v, want resolved %s", got[0], want)
+	}
+	if got[1].Resolved != "" || got[1].Error == nil || got[1].Error.Code != "ERR_PACKAGE_PATH_NOT_EXPORTED" {
+		t.Errorf("results[1] = %+v, want ERR_PACKAGE_PATH_NOT_EXPORTED", got[1])
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("unexpected stderr: %s", stderr.String())
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	resolve "github.com/startracex/node-resolve"
+)
+
+// JSON-RPC 2.0 error codes. codeResolveFailed carries the Node error code of the
+// failure in its data.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeResolveFailed  = -32000
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+type resolveParams struct {
+	Specifier string `json:"specifier"`
+	Base      string `json:"base"`
+}
+
+type listExportsParams struct {
+	Dir        string   `json:"dir"`
+	Conditions []string `json:"conditions"`
+}
+
+type exportEntry struct {
+	Subpath string              `json:"subpath"`
+	Pattern string              `json:"pattern,omitempty"`
+	Targets map[string][]string `json:"targets,omitempty"`
+	Blocked bool                `json:"blocked,omitempty"`
+}
+
+// server answers JSON-RPC requests with a resolver whose cache lives until an
+// invalidate or configure request.
+type server struct {
+	base     string
+	config   config
+	resolver *resolve.ModuleResolver
+}
+
+func newServer(base string, config config) *server {
+	config.normalize()
+	return &server{base: base, config: config, resolver: config.newResolver()}
+}
+
+// serve handles one request per line of in until it ends. Methods:
+//
+//	resolve         {specifier, base?} -> {resolved}
+//	resolveImports  {specifier, base?} -> {resolved}
+//	listExports     {dir, conditions?} -> [{subpath, pattern, targets, blocked}]
+//	invalidate      {}                 -> {}
+//	configure       {extensions, conditions, ...} -> the configuration in effect
+//
+// Relative bases and directories are taken from the -from directory. listExports
+// expands pattern subpaths against the files of the package, naming the pattern
+// each comes from. configure replaces the options it is given and keeps the others.
+func (s *server) serve(in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	encoder := json.NewEncoder(out)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if res := s.handle(line); res != nil {
+				if err := encoder.Encode(res); err != nil {
+					return err
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) handle(line []byte) *response {
+	res := &response{JSONRPC: "2.0", ID: json.RawMessage("null")}
+
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			res.Error = &rpcError{Code: codeParseError, Message: err.Error()}
+		} else {
+			res.Error = &rpcError{Code: codeInvalidRequest, Message: err.Error()}
+		}
+		return res
+	}
+	if req.ID != nil {
+		res.ID = req.ID
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		res.Error = &rpcError{Code: codeInvalidRequest, Message: "invalid request"}
+		return res
+	}
+
+	result, err := s.call(req.Method, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		var e *rpcError
+		if !errors.As(err, &e) {
+			e = &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		res.Error = e
+		return res
+	}
+	res.Result = result
+	return res
+}
+
+func (s *server) call(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "resolve", "resolveImports":
+		var p resolveParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		base := s.path(p.Base)
+		if method == "resolveImports" && !strings.HasPrefix(p.Specifier, "#") {
+			return nil, resolveFailed(&resolve.ResolveError{
+				Code:      resolve.CodePackageImportNotDefined,
+				Specifier: p.Specifier,
+				Base:      base,
+			})
+		}
+		resolved, err := s.resolver.TryResolve(p.Specifier, base)
+		if err != nil {
+			return nil, resolveFailed(err)
+		}
+		return map[string]string{"resolved": resolved}, nil
+
+	case "listExports":
+		var p listExportsParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if p.Dir == "" {
+			return nil, errors.New("missing dir")
+		}
+		return s.listExports(s.path(p.Dir), p.Conditions)
+
+	case "invalidate":
+		s.resolver.ClearCache()
+		return struct{}{}, nil
+
+	case "configure":
+		if len(params) == 0 {
+			params = json.RawMessage("{}")
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(params, &fields); err != nil {
+			return nil, err
+		}
+		// Decode into a deep copy, as Unmarshal reuses the backing arrays of slices
+		// and merges into maps.
+		var next config
+		current, err := json.Marshal(s.config)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(current, &next); err != nil {
+			return nil, err
+		}
+		if _, ok := fields["extensionMap"]; ok {
+			next.ExtensionMap = nil
+		}
+		if _, ok := fields["mainFieldConditions"]; ok {
+			next.MainFieldConditions = nil
+		}
+		if err := decodeParams(params, &next); err != nil {
+			return nil, err
+		}
+		next.normalize()
+		s.config = next
+		s.resolver = next.newResolver()
+		return s.config, nil
+	}
+
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", method)}
+}
+
+func (s *server) listExports(dir string, conditions []string) ([]exportEntry, error) {
+	_, scope, err := s.resolver.FindPackageScope(dir)
+	if err != nil {
+		return nil, resolveFailed(err)
+	}
+	if filepath.Clean(scope) != filepath.Clean(dir) {
+		return nil, fmt.Errorf("no %s in %s", s.resolver.Config.ManifestFileName, dir)
+	}
+
+	// PackageEntryPoints lists targets under the configured conditions.
+	resolver := s.resolver
+	if conditions != nil {
+		config := *s.resolver.Config
+		config.Conditions = conditions
+		resolver = resolve.NewModuleResolver(&config)
+	}
+	points, err := resolver.PackageEntryPoints(dir)
+	if errors.Is(err, resolve.ErrInvalidExports) {
+		return nil, resolveFailed(&resolve.ResolveError{
+			Code: resolve.CodeInvalidPackageConfig,
+			Base: dir,
+			Path: filepath.Join(dir, s.resolver.Config.ManifestFileName),
+			Err:  err,
+		})
+	}
+	if err != nil {
+		return nil, resolveFailed(err)
+	}
+
+	entries := make([]exportEntry, 0, len(points))
+	for _, point := range points {
+		entries = append(entries, exportEntry{
+			Subpath: point.Subpath,
+			Pattern: point.Pattern,
+			Targets: point.Targets,
+			Blocked: point.Blocked,
+		})
+	}
+	return entries, nil
+}
+
+func (s *server) path(p string) string {
+	if p == "" {
+		return s.base
+	}
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(s.base, p)
+}
+
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		params = json.RawMessage("{}")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(params))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+func resolveFailed(err error) *rpcError {
+	e := &rpcError{Code: codeResolveFailed, Message: err.Error()}
+	var resolveErr *resolve.ResolveError
+	var manifestErr *resolve.ManifestError
+	switch {
+	case errors.As(err, &resolveErr):
+		e.Data = map[string]string{"code": resolveErr.Code}
+	case errors.As(err, &manifestErr):
+		e.Data = map[string]string{"code": resolve.CodeInvalidPackageConfig}
+	}
+	return e
+}
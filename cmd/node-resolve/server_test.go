@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"package.json":                      `{"imports": {"#util": "./util.js"}}`,
+		"util.js":                           "",
+		"main.ts":                           "",
+		"node_modules/pkg/package.json":     `{"exports": {".": {"browser": "./browser.js", "default": "./index.js"}, "./internal": null, "./features/*": "./src/*.js", "./features/private/*": null}}`,
+		"node_modules/pkg/src/a.js":         "",
+		"node_modules/pkg/src/private/b.js": "",
+		"node_modules/pkg/browser.js":       "",
+		"node_modules/pkg/index.js":         "",
+		"node_modules/plain/package.json":   `{"exports": {"default": "./index.js"}}`,
+		"node_modules/plain/index.js":       "",
+	})
+
+	requests := []string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "resolve", "params": {"specifier": "pkg"}}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "resolveImports", "params": {"specifier": "#util", "base": "."}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "resolve", "params": {"specifier": "pkg/internal"}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "resolveImports", "params": {"specifier": "pkg"}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "listExports", "params": {"dir": "node_modules/pkg", "conditions": ["browser"]}}`,
+		`{"jsonrpc": "2.0", "method": "invalidate"}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "configure", "params": {"conditions": ["browser"], "extensionMap": {".js": [".ts"]}}}`,
+		`{"jsonrpc": "2.0", "id": 7, "method": "resolve", "params": {"specifier": "pkg"}}`,
+		`{"jsonrpc": "2.0", "id": 8, "method": "resolve", "params": {"specifier": "./main.js"}}`,
+		`{"jsonrpc": "2.0", "id": 9, "method": "configure", "params": {"extensions": 1}}`,
+		`{"jsonrpc": "2.0", "id": 10, "method": "nope"}`,
+		`{"jsonrpc": "2.0", "id": 11, "method": "resolve", "params": {"specifier": "pkg", "extra": true}}`,
+		`{"jsonrpc": "2.0", "id": 12`,
+		``,
+		`{"jsonrpc": "2.0", "id": 13, "method": "resolve", "params": {"specifier": "plain"}}`,
+		`{"jsonrpc": "2.0", "id": 14, "method": "configure"}`,
+		`{"jsonrpc": "2.0", "id": "last", "method": "invalidate"}`,
+	}
+
+	var out bytes.Buffer
+	s := newServer(root, config{})
+	if err := s.serve(strings.NewReader(strings.Join(requests, "\n")), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	type responseError struct {
+		Code int               `json:"code"`
+		Data map[string]string `json:"data"`
+	}
+	type testResponse struct {
+		ID     any             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *responseError  `json:"error"`
+	}
+	var got []testResponse
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var res testResponse
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			t.Fatalf("invalid response %s: %v", scanner.Text(), err)
+		}
+		got = append(got, res)
+	}
+
+	result := func(v any) json.RawMessage {
+		data, _ := json.Marshal(v)
+		return data
+	}
+	pkg := filepath.Join(root, "node_modules", "pkg")
+	want := []testResponse{
+		{ID: 1.0, Result: result(map[string]string{"resolved": filepath.Join(pkg, "index.js")})},
+		{ID: 2.0, Result: result(map[string]string{"resolved": filepath.Join(root, "util.js")})},
+		{ID: 3.0, Error: &responseError{Code: codeResolveFailed, Data: map[string]string{"code": "ERR_PACKAGE_PATH_NOT_EXPORTED"}}},
+		{ID: 4.0, Error: &responseError{Code: codeResolveFailed, Data: map[string]string{"code": "ERR_PACKAGE_IMPORT_NOT_DEFINED"}}},
+		{ID: 5.0, Result: result([]exportEntry{
+			{Subpath: ".", Targets: map[string][]string{"browser": {"./browser.js"}}},
+			{Subpath: "./features/a", Pattern: "./features/*", Targets: map[string][]string{"browser": {"./src/a.js"}}},
+			{Subpath: "./features/private/*", Blocked: true},
+			{Subpath: "./features/private/b", Pattern: "./features/private/*", Blocked: true},
+			{Subpath: "./internal", Blocked: true},
+		})},
+		{ID: 6.0, Result: result(config{Conditions: []string{"browser", "default"}, ExtensionMap: map[string][]string{".js": {".ts"}}})},
+		{ID: 7.0, Result: result(map[string]string{"resolved": filepath.Join(pkg, "browser.js")})},
+		{ID: 8.0, Result: result(map[string]string{"resolved": filepath.Join(root, "main.ts")})},
+		{ID: 9.0, Error: &responseError{Code: codeInvalidParams}},
+		{ID: 10.0, Error: &responseError{Code: codeMethodNotFound}},
+		{ID: 11.0, Error: &responseError{Code: codeInvalidParams}},
+		{ID: nil, Error: &responseError{Code: codeParseError}},
+		{ID: 13.0, Result: result(map[string]string{"resolved": filepath.Join(root, "node_modules", "plain", "index.js")})},
+		{ID: 14.0, Result: result(config{Conditions: []string{"browser", "default"}, ExtensionMap: map[string][]string{".js": {".ts"}}})},
+		{ID: "last", Result: result(struct{}{})},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d responses, want %d:\n%s", len(got), len(want), out.String())
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("response %d = %+v (%s), want %+v (%s)", i, got[i], got[i].Result, want[i], want[i].Result)
+		}
+	}
+}
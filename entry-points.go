@@ -0,0 +1,139 @@
+package resolve
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// PackageEntryPoints lists the public entry points of the package in pkgDir under
+// the configured conditions. Pattern keys are expanded against the files of the
+// package when the FS implements ReadDirFS; subpaths owned by a null pattern are
+// reported as blocked. Packages without exports have no enumerable entry points.
+func (r *ModuleResolver) PackageEntryPoints(pkgDir string) ([]ExportEntry, error) {
+	manifest, err := r.readManifest(context.Background(), r.Config.Path.Join(pkgDir, r.Config.ManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	exports, ok := manifest.Exports()
+	if !ok {
+		return nil, nil
+	}
+	mapping, err := NormalizeExports(exports)
+	if err != nil {
+		return nil, err
+	}
+
+	subpathResolver := &SubpathResolver{
+		Conditions:     r.Config.Conditions,
+		Exports:        mapping,
+		ProbeFallbacks: r.Config.ProbeFallbacks,
+	}
+	conditions := r.Config.Conditions
+	if conditions == nil {
+		conditions = []string{"default"}
+	}
+
+	var files []string
+	listed := false
+	var entries []ExportEntry
+	seen := make(map[string]struct{})
+	add := func(entry ExportEntry) {
+		if _, ok := seen[entry.Subpath]; ok {
+			return
+		}
+		seen[entry.Subpath] = struct{}{}
+		entries = append(entries, entry)
+	}
+
+	for _, entry := range subpathResolver.ListExports(conditions) {
+		if entry.Blocked || !strings.ContainsRune(entry.Subpath, '*') {
+			add(entry)
+			continue
+		}
+
+		targets := patternTargets(entry.Targets)
+		if !listed {
+			files, listed = r.listFiles(pkgDir), true
+		}
+		if len(targets) == 0 || files == nil {
+			add(entry)
+			continue
+		}
+
+		key := entry.Subpath
+		for _, target := range targets {
+			before, after, _ := strings.Cut(target, "*")
+			for _, file := range files {
+				file = subpathPrefix + file
+				if len(file) < len(before)+len(after) || !strings.HasPrefix(file, before) || !strings.HasSuffix(file, after) {
+					continue
+				}
+				wildcard := file[len(before) : len(file)-len(after)]
+				subpath := strings.Replace(key, "*", wildcard, 1)
+				if _, exact := mapping[subpath]; exact {
+					continue
+				}
+				owner, _, _ := findWildcardMatch(mapping, subpath)
+				switch {
+				case owner == key:
+					expanded := subpathResolver.exportEntry(subpath, conditions)
+					expanded.Pattern = key
+					add(expanded)
+				case mapping[owner] == nil:
+					add(ExportEntry{Subpath: subpath, Pattern: owner, Blocked: true})
+				}
+			}
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b ExportEntry) int {
+		return strings.Compare(a.Subpath, b.Subpath)
+	})
+	return entries, nil
+}
+
+// patternTargets returns the distinct pattern targets of every condition, so that
+// subpaths only reachable under some conditions are expanded too.
+func patternTargets(targets map[string][]string) []string {
+	var result []string
+	for _, cond := range slices.Sorted(maps.Keys(targets)) {
+		for _, target := range targets[cond] {
+			if strings.HasPrefix(target, subpathPrefix) && strings.ContainsRune(target, '*') && !slices.Contains(result, target) {
+				result = append(result, target)
+			}
+		}
+	}
+	return result
+}
+
+// listFiles returns the files below dir as slash-separated relative paths, skipping
+// modules directories, or nil when the FS cannot list directories.
+func (r *ModuleResolver) listFiles(dir string) []string {
+	dirFS, ok := r.Config.FS.(ReadDirFS)
+	if !ok {
+		return nil
+	}
+
+	files := []string{}
+	var walk func(dir, prefix string)
+	walk = func(dir, prefix string) {
+		entries, err := dirFS.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() {
+				if name != r.Config.ModulesDirectoryName {
+					walk(r.Config.Path.Join(dir, name), prefix+name+"/")
+				}
+				continue
+			}
+			files = append(files, prefix+name)
+		}
+	}
+	walk(dir, "")
+	return files
+}
@@ -0,0 +1,102 @@
+package resolve
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPackageEntryPoints(t *testing.T) {
+	r := newMemResolver(map[string]string{
+		"pkg/package.json": `{
+			"exports": {
+				".": {"import": "./index.mjs", "require": "./index.cjs"},
+				"./utils/*": "./src/utils/*.js",
+				"./utils/private/*": null,
+				"./package.json": "./package.json"
+			}
+		}`,
+		"pkg/src/utils/a.js":                "",
+		"pkg/src/utils/b/c.js":              "",
+		"pkg/src/utils/private/x.js":        "",
+		"pkg/src/utils/readme.md":           "",
+		"pkg/node_modules/dep/src/utils.js": "",
+	}, ResolverConfig{Conditions: []string{"import", "require"}})
+
+	got, err := r.PackageEntryPoints("/pkg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	both := func(target string) map[string][]string {
+		return map[string][]string{"import": {target}, "require": {target}}
+	}
+	want := []ExportEntry{
+		{Subpath: ".", Targets: map[string][]string{"import": {"./index.mjs"}, "require": {"./index.cjs"}}},
+		{Subpath: "./package.json", Targets: both("./package.json")},
+		{Subpath: "./utils/a", Pattern: "./utils/*", Targets: both("./src/utils/a.js")},
+		{Subpath: "./utils/b/c", Pattern: "./utils/*", Targets: both("./src/utils/b/c.js")},
+		{Subpath: "./utils/private/*", Blocked: true},
+		{Subpath: "./utils/private/x", Pattern: "./utils/private/*", Blocked: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PackageEntryPoints() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestPackageEntryPointsConditionalPatterns(t *testing.T) {
+	r := newMemResolver(map[string]string{
+		"pkg/package.json": `{"exports": {"./*": {"import": "./esm/*.mjs", "require": "./cjs/*.cjs"}}}`,
+		"pkg/esm/a.mjs":    "",
+		"pkg/esm/b.mjs":    "",
+		"pkg/cjs/a.cjs":    "",
+		"pkg/cjs/c.cjs":    "",
+	}, ResolverConfig{Conditions: []string{"import", "require"}})
+
+	got, err := r.PackageEntryPoints("/pkg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	targets := func(name string) map[string][]string {
+		return map[string][]string{"import": {"./esm/" + name + ".mjs"}, "require": {"./cjs/" + name + ".cjs"}}
+	}
+	want := []ExportEntry{
+		{Subpath: "./a", Pattern: "./*", Targets: targets("a")},
+		{Subpath: "./b", Pattern: "./*", Targets: targets("b")},
+		{Subpath: "./c", Pattern: "./*", Targets: targets("c")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PackageEntryPoints() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestPackageEntryPointsWithoutReadDir(t *testing.T) {
+	files := memFS{
+		"pkg/package.json": &fstest.MapFile{Data: []byte(`{"exports": {"./*": "./src/*.js"}}`)},
+		"pkg/src/a.js":     &fstest.MapFile{},
+	}
+	r := NewModuleResolver(&ResolverConfig{FS: statOnlyFS{files}, Path: posixPath{}})
+
+	got, err := r.PackageEntryPoints("/pkg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []ExportEntry{
+		{Subpath: "./*", Targets: map[string][]string{"default": {"./src/*.js"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PackageEntryPoints() = %+v, want %+v", got, want)
+	}
+}
+
+type statOnlyFS struct {
+	files memFS
+}
+
+func (f statOnlyFS) Stat(name string) (fs.FileInfo, error) {
+	return f.files.Stat(name)
+}
+
+func (f statOnlyFS) ReadFile(name string) ([]byte, error) {
+	return f.files.ReadFile(name)
+}
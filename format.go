@@ -0,0 +1,62 @@
+package resolve
+
+import (
+	"path"
+)
+
+const (
+	FormatBuiltin            = "builtin"
+	FormatModule             = "module"
+	FormatCommonJS           = "commonjs"
+	FormatJSON               = "json"
+	FormatWASM               = "wasm"
+	FormatAddon              = "addon"
+	FormatModuleTypeScript   = "module-typescript"
+	FormatCommonJSTypeScript = "commonjs-typescript"
+)
+
+// Format reports the module format of a resolved file following Node's ESM_FILE_FORMAT:
+// .mjs/.cjs/.mts/.cts are fixed, .js/.ts and extensionless files follow the "type"
+// field of the nearest manifest. Unknown extensions return an empty string.
+func (r *ModuleResolver) Format(file string) string {
+	if r.Config.IsCoreModule(file) {
+		return FormatBuiltin
+	}
+
+	switch path.Ext(file) {
+	case ".mjs":
+		return FormatModule
+	case ".cjs":
+		return FormatCommonJS
+	case ".mts":
+		return FormatModuleTypeScript
+	case ".cts":
+		return FormatCommonJSTypeScript
+	case ".json":
+		return FormatJSON
+	case ".wasm":
+		return FormatWASM
+	case ".node":
+		return FormatAddon
+	case ".js", "":
+		if r.packageType(file) == "module" {
+			return FormatModule
+		}
+		return FormatCommonJS
+	case ".ts":
+		if r.packageType(file) == "module" {
+			return FormatModuleTypeScript
+		}
+		return FormatCommonJSTypeScript
+	}
+
+	return ""
+}
+
+func (r *ModuleResolver) packageType(file string) string {
+	manifest, _, err := r.FindPackageScope(r.Config.Path.Dir(file))
+	if err != nil {
+		return ""
+	}
+	return manifest.Type()
+}
@@ -0,0 +1,44 @@
+package resolve
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"esm/package.json":  `{"type": "module"}`,
+		"cjs/package.json":  `{"type": "commonjs"}`,
+		"none/package.json": `{}`,
+	})
+	r := NewModuleResolver(&ResolverConfig{})
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"node:fs", FormatBuiltin},
+		{filepath.Join(root, "esm", "a.js"), FormatModule},
+		{filepath.Join(root, "esm", "lib", "a.js"), FormatModule},
+		{filepath.Join(root, "esm", "bin"), FormatModule},
+		{filepath.Join(root, "esm", "a.cjs"), FormatCommonJS},
+		{filepath.Join(root, "esm", "a.ts"), FormatModuleTypeScript},
+		{filepath.Join(root, "esm", "a.cts"), FormatCommonJSTypeScript},
+		{filepath.Join(root, "cjs", "a.js"), FormatCommonJS},
+		{filepath.Join(root, "cjs", "a.mjs"), FormatModule},
+		{filepath.Join(root, "cjs", "a.ts"), FormatCommonJSTypeScript},
+		{filepath.Join(root, "cjs", "a.mts"), FormatModuleTypeScript},
+		{filepath.Join(root, "none", "a.js"), FormatCommonJS},
+		{filepath.Join(root, "none", "a.json"), FormatJSON},
+		{filepath.Join(root, "none", "a.wasm"), FormatWASM},
+		{filepath.Join(root, "none", "a.node"), FormatAddon},
+		{filepath.Join(root, "none", "a.css"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := r.Format(tt.file); got != tt.want {
+				t.Errorf("Format(%q) = %q, want %q", tt.file, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// Package lint checks the exports and imports fields of a package manifest.
+package lint
+
+import (
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+
+	resolve "github.com/startracex/node-resolve"
+)
+
+const (
+	RuleInvalidTarget        = "invalid-target"
+	RuleMissingTarget        = "missing-target"
+	RuleUnreachableCondition = "unreachable-condition"
+	RuleDefaultNotLast       = "default-not-last"
+	RuleTypesNotFirst        = "types-not-first"
+	RuleMultipleWildcards    = "multiple-wildcards"
+	RuleImportsKeyPrefix     = "imports-key-prefix"
+	RuleMixedKeys            = "mixed-keys"
+	RuleInvalidField         = "invalid-field"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a problem found in the manifest. Pointer is a JSON pointer to the
+// offending value.
+type Diagnostic struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Pointer  string `json:"pointer"`
+	Message  string `json:"message"`
+}
+
+type linter struct {
+	resolver    *resolve.ModuleResolver
+	dir         string
+	diagnostics []Diagnostic
+}
+
+// Package lints the manifest in pkgDir, checking targets against the files read
+// through the resolver's FS.
+func Package(r *resolve.ModuleResolver, pkgDir string) ([]Diagnostic, error) {
+	data, err := r.Config.FS.ReadFile(r.Config.Path.Join(pkgDir, r.Config.ManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	root, err := resolve.ParseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	if root.Kind != resolve.JSONObject {
+		return nil, errors.New("lint: manifest is not an object")
+	}
+
+	l := &linter{resolver: r, dir: pkgDir}
+	if exports := root.Get("exports"); exports != nil {
+		l.exports(exports, "/exports")
+	}
+	if imports := root.Get("imports"); imports != nil {
+		l.imports(imports, "/imports")
+	}
+	return l.diagnostics, nil
+}
+
+func (l *linter) report(rule, severity, pointer, message string) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Rule:     rule,
+		Severity: severity,
+		Pointer:  pointer,
+		Message:  message,
+	})
+}
+
+func (l *linter) exports(v *resolve.JSONValue, pointer string) {
+	if v.Kind != resolve.JSONObject {
+		l.target(v, pointer, false)
+		return
+	}
+
+	if _, err := resolve.NormalizeExports(v); err != nil {
+		l.report(RuleMixedKeys, SeverityError, pointer, "exports must not mix subpath keys and condition keys")
+		return
+	}
+	if len(v.Keys) > 0 && !strings.HasPrefix(v.Keys[0], ".") {
+		l.target(v, pointer, false)
+		return
+	}
+
+	for i, key := range v.Keys {
+		keyPointer := pointer + "/" + escape(key)
+		l.wildcards(key, keyPointer)
+		l.target(v.Values[i], keyPointer, false)
+	}
+}
+
+func (l *linter) imports(v *resolve.JSONValue, pointer string) {
+	if v.Kind != resolve.JSONObject {
+		l.report(RuleInvalidField, SeverityError, pointer, "imports must be an object")
+		return
+	}
+
+	for i, key := range v.Keys {
+		keyPointer := pointer + "/" + escape(key)
+		if !strings.HasPrefix(key, "#") || key == "#" || strings.HasPrefix(key, "#/") {
+			l.report(RuleImportsKeyPrefix, SeverityError, keyPointer, "imports key "+strconv.Quote(key)+" must start with \"#\" followed by a name")
+		}
+		l.wildcards(key, keyPointer)
+		l.target(v.Values[i], keyPointer, true)
+	}
+}
+
+func (l *linter) wildcards(key, pointer string) {
+	if strings.Count(key, "*") > 1 {
+		l.report(RuleMultipleWildcards, SeverityError, pointer, "key "+strconv.Quote(key)+" contains more than one \"*\"")
+	}
+}
+
+// target checks every target of v. The manifest is walked here rather than through
+// the matrices of resolve.SubpathResolver, which skip the targets a linter must
+// report: invalid ones, fallbacks after the first valid one and conditions after
+// "default", and which lose the array indices of the JSON pointers.
+func (l *linter) target(v *resolve.JSONValue, pointer string, imports bool) {
+	switch v.Kind {
+	case resolve.JSONString:
+		l.targetString(v.Str, pointer, imports)
+	case resolve.JSONArray:
+		for i, item := range v.Items {
+			l.target(item, pointer+"/"+strconv.Itoa(i), imports)
+		}
+	case resolve.JSONObject:
+		l.conditions(v, pointer, imports)
+	case resolve.JSONNull:
+	default:
+		l.report(RuleInvalidTarget, SeverityError, pointer, "target must be a string, an array, an object or null")
+	}
+}
+
+func (l *linter) conditions(v *resolve.JSONValue, pointer string, imports bool) {
+	defaultIndex := -1
+	for i, key := range v.Keys {
+		keyPointer := pointer + "/" + escape(key)
+		switch {
+		case strings.HasPrefix(key, "."):
+			l.report(RuleMixedKeys, SeverityError, keyPointer, "conditions must not contain subpath key "+strconv.Quote(key))
+		case key == "types" && i != 0:
+			l.report(RuleTypesNotFirst, SeverityWarning, keyPointer, "\"types\" should be the first condition")
+		case key == "default" && i != len(v.Keys)-1:
+			l.report(RuleDefaultNotLast, SeverityError, keyPointer, "\"default\" must be the last condition")
+		}
+		if defaultIndex != -1 {
+			l.report(RuleUnreachableCondition, SeverityError, keyPointer, "condition "+strconv.Quote(key)+" is unreachable after \"default\"")
+		}
+		if key == "default" && defaultIndex == -1 {
+			defaultIndex = i
+		}
+		l.target(v.Values[i], keyPointer, imports)
+	}
+}
+
+func (l *linter) targetString(target, pointer string, imports bool) {
+	if !resolve.ValidTarget(target, imports) {
+		message := "target " + strconv.Quote(target) + " must start with \"./\" and stay inside the package"
+		if imports {
+			message = "target " + strconv.Quote(target) + " must be a relative path starting with \"./\" or a package specifier"
+		}
+		l.report(RuleInvalidTarget, SeverityError, pointer, message)
+		return
+	}
+	if !strings.HasPrefix(target, "./") {
+		return
+	}
+
+	exists := false
+	if before, after, ok := strings.Cut(target, "*"); ok {
+		exists = l.patternExists(before, after)
+	} else {
+		stat, err := l.resolver.Config.FS.Stat(l.resolver.Config.Path.Join(l.dir, target))
+		exists = err == nil && !stat.IsDir()
+	}
+	if !exists {
+		l.report(RuleMissingTarget, SeverityError, pointer, "target "+strconv.Quote(target)+" does not exist")
+	}
+}
+
+// patternExists reports whether a file of the package matches the pattern target
+// before*after. Without a ReadDirFS, the directory the pattern starts in must exist.
+func (l *linter) patternExists(before, after string) bool {
+	config := l.resolver.Config
+	dir := path.Dir(before)
+	dirFS, ok := config.FS.(resolve.ReadDirFS)
+	if !ok {
+		stat, err := config.FS.Stat(config.Path.Join(l.dir, dir))
+		return err == nil && stat.IsDir()
+	}
+
+	var walk func(dir string) bool
+	walk = func(dir string) bool {
+		entries, err := dirFS.ReadDir(config.Path.Join(l.dir, dir))
+		if err != nil {
+			return false
+		}
+		for _, entry := range entries {
+			name := path.Join(dir, entry.Name())
+			if entry.IsDir() {
+				if entry.Name() != config.ModulesDirectoryName && walk(name) {
+					return true
+				}
+				continue
+			}
+			file := "./" + name
+			if len(file) >= len(before)+len(after) && strings.HasPrefix(file, before) && strings.HasSuffix(file, after) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(dir)
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func escape(key string) string {
+	return pointerEscaper.Replace(key)
+}
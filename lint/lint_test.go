@@ -0,0 +1,116 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	resolve "github.com/startracex/node-resolve"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestPackage(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     []Diagnostic
+	}{
+		{
+			name: "valid",
+			manifest: `{
+				"exports": {
+					".": {"types": "./index.d.ts", "import": "./index.mjs", "default": "./index.js"},
+					"./lib/*": "./lib/*.js",
+					"./private/*": null
+				},
+				"imports": {"#dep": {"node": "dep", "default": "./index.js"}}
+			}`,
+		},
+		{
+			name:     "invalid targets",
+			manifest: `{"exports": {".": "index.js", "./up": "./../x.js", "./missing": "./missing.js", "./lib/*": "./nolib/*.js", "./dir": "./lib", "./mjs/*": "./lib/*.mjs"}}`,
+			want: []Diagnostic{
+				{Rule: RuleInvalidTarget, Severity: SeverityError, Pointer: "/exports/."},
+				{Rule: RuleInvalidTarget, Severity: SeverityError, Pointer: "/exports/.~1up"},
+				{Rule: RuleMissingTarget, Severity: SeverityError, Pointer: "/exports/.~1missing"},
+				{Rule: RuleMissingTarget, Severity: SeverityError, Pointer: "/exports/.~1lib~1*"},
+				{Rule: RuleMissingTarget, Severity: SeverityError, Pointer: "/exports/.~1dir"},
+				{Rule: RuleMissingTarget, Severity: SeverityError, Pointer: "/exports/.~1mjs~1*"},
+			},
+		},
+		{
+			name:     "condition order",
+			manifest: `{"exports": {"import": "./index.mjs", "default": "./index.js", "types": "./index.d.ts"}}`,
+			want: []Diagnostic{
+				{Rule: RuleDefaultNotLast, Severity: SeverityError, Pointer: "/exports/default"},
+				{Rule: RuleTypesNotFirst, Severity: SeverityWarning, Pointer: "/exports/types"},
+				{Rule: RuleUnreachableCondition, Severity: SeverityError, Pointer: "/exports/types"},
+			},
+		},
+		{
+			name:     "wildcards and arrays",
+			manifest: `{"exports": {"./*/*": ["./lib/*.js", "lib/*.js"]}}`,
+			want: []Diagnostic{
+				{Rule: RuleMultipleWildcards, Severity: SeverityError, Pointer: "/exports/.~1*~1*"},
+				{Rule: RuleInvalidTarget, Severity: SeverityError, Pointer: "/exports/.~1*~1*/1"},
+			},
+		},
+		{
+			name:     "mixed keys",
+			manifest: `{"exports": {".": "./index.js", "import": "./index.mjs"}}`,
+			want: []Diagnostic{
+				{Rule: RuleMixedKeys, Severity: SeverityError, Pointer: "/exports"},
+			},
+		},
+		{
+			name:     "imports keys",
+			manifest: `{"imports": {"dep": "./index.js", "#": "./index.js", "#ok": "/abs.js"}}`,
+			want: []Diagnostic{
+				{Rule: RuleImportsKeyPrefix, Severity: SeverityError, Pointer: "/imports/dep"},
+				{Rule: RuleImportsKeyPrefix, Severity: SeverityError, Pointer: "/imports/#"},
+				{Rule: RuleInvalidTarget, Severity: SeverityError, Pointer: "/imports/#ok"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{
+				"package.json": tt.manifest,
+				"index.js":     "",
+				"index.mjs":    "",
+				"index.d.ts":   "",
+				"lib/a.js":     "",
+			})
+			r := resolve.NewModuleResolver(&resolve.ResolverConfig{})
+			got, err := Package(r, dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i := range got {
+				if got[i].Message == "" {
+					t.Errorf("diagnostic %+v has no message", got[i])
+				}
+				got[i].Message = ""
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Package() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
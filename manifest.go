@@ -0,0 +1,214 @@
+package resolve
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+var ErrPackageScopeNotFound = errors.New("resolve: package scope not found")
+
+type ManifestError struct {
+	Path string
+	Err  error
+
+	// unread marks a manifest that could not be read, rather than parsed.
+	unread bool
+}
+
+func (e *ManifestError) Error() string {
+	return "resolve: invalid manifest " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *ManifestError) Unwrap() error {
+	return e.Err
+}
+
+// Manifest is a parsed package.json. Raw holds the decoded document for fields
+// without a typed accessor.
+type Manifest struct {
+	Raw map[string]any
+
+	// ordered is the document with its key order, kept when it has exports or
+	// imports, whose conditions take precedence in the order they are written.
+	ordered *JSONValue
+}
+
+func ParseManifest(data []byte) (*Manifest, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		raw = make(map[string]any)
+	}
+	m := &Manifest{Raw: raw}
+	_, hasExports := raw["exports"]
+	_, hasImports := raw["imports"]
+	if hasExports || hasImports {
+		ordered, err := ParseJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		m.ordered = ordered
+	}
+	return m, nil
+}
+
+func (m *Manifest) Field(name string) (any, bool) {
+	value, ok := m.Raw[name]
+	return value, ok
+}
+
+func (m *Manifest) stringField(name string) string {
+	value, _ := m.Raw[name].(string)
+	return value
+}
+
+func (m *Manifest) stringMapField(name string) map[string]string {
+	value, ok := m.Raw[name].(map[string]any)
+	if !ok {
+		return nil
+	}
+	result := make(map[string]string, len(value))
+	for k, v := range value {
+		if str, ok := v.(string); ok {
+			result[k] = str
+		}
+	}
+	return result
+}
+
+func toStrings(value any) []string {
+	arr, ok := value.([]any)
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(arr))
+	for _, item := range arr {
+		if str, ok := item.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
+
+func (m *Manifest) Name() string {
+	return m.stringField("name")
+}
+
+func (m *Manifest) Version() string {
+	return m.stringField("version")
+}
+
+func (m *Manifest) Type() string {
+	return m.stringField("type")
+}
+
+func (m *Manifest) Main() string {
+	return m.stringField("main")
+}
+
+func (m *Manifest) Module() string {
+	return m.stringField("module")
+}
+
+// Browser returns the browser field, either as a replacement main or as a map of
+// module replacements, in which an empty string stands for false.
+func (m *Manifest) Browser() (main string, replacements map[string]string) {
+	switch v := m.Raw["browser"].(type) {
+	case string:
+		return v, nil
+	case map[string]any:
+		replacements = make(map[string]string, len(v))
+		for k, target := range v {
+			str, _ := target.(string)
+			replacements[k] = str
+		}
+		return "", replacements
+	}
+	return "", nil
+}
+
+// Exports returns the exports field. For a manifest read by ParseManifest it is a
+// *JSONValue, which keeps the order of conditions.
+func (m *Manifest) Exports() (any, bool) {
+	return m.orderedField("exports")
+}
+
+// Imports returns the imports field, as a *JSONValue like Exports.
+func (m *Manifest) Imports() (any, bool) {
+	return m.orderedField("imports")
+}
+
+func (m *Manifest) orderedField(name string) (any, bool) {
+	if m.ordered != nil {
+		if value := m.ordered.Get(name); value != nil {
+			return value, value.Kind != JSONNull
+		}
+	}
+	value, ok := m.Raw[name]
+	return value, ok && value != nil
+}
+
+// Types returns the types field, falling back to typings.
+func (m *Manifest) Types() string {
+	if types := m.stringField("types"); types != "" {
+		return types
+	}
+	return m.stringField("typings")
+}
+
+func (m *Manifest) TypesVersions() map[string]map[string][]string {
+	value, ok := m.Raw["typesVersions"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	result := make(map[string]map[string][]string, len(value))
+	for version, paths := range value {
+		pathsMap, ok := paths.(map[string]any)
+		if !ok {
+			continue
+		}
+		result[version] = make(map[string][]string, len(pathsMap))
+		for pattern, targets := range pathsMap {
+			result[version][pattern] = toStrings(targets)
+		}
+	}
+	return result
+}
+
+// SideEffects reports whether every module of the package may have side effects,
+// or lists the patterns of those that may. A missing field means all.
+func (m *Manifest) SideEffects() (all bool, patterns []string) {
+	switch v := m.Raw["sideEffects"].(type) {
+	case bool:
+		return v, nil
+	case []any:
+		return false, toStrings(v)
+	}
+	return true, nil
+}
+
+func (m *Manifest) Dependencies() map[string]string {
+	return m.stringMapField("dependencies")
+}
+
+func (m *Manifest) PeerDependencies() map[string]string {
+	return m.stringMapField("peerDependencies")
+}
+
+func (m *Manifest) OptionalDependencies() map[string]string {
+	return m.stringMapField("optionalDependencies")
+}
+
+// Workspaces returns the workspace patterns from either the array form or the
+// object form with a packages key.
+func (m *Manifest) Workspaces() []string {
+	switch v := m.Raw["workspaces"].(type) {
+	case []any:
+		return toStrings(v)
+	case map[string]any:
+		return toStrings(v["packages"])
+	}
+	return nil
+}
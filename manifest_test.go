@@ -0,0 +1,101 @@
+package resolve
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	m, err := ParseManifest([]byte(`{
+		"name": "pkg",
+		"version": "1.2.3",
+		"type": "module",
+		"main": "./main.cjs",
+		"module": "./main.mjs",
+		"browser": {"./main.cjs": "./browser.js", "fs": false},
+		"exports": {".": "./main.mjs"},
+		"imports": null,
+		"typings": "./main.d.ts",
+		"typesVersions": {">=4": {"*": ["ts4/*"]}},
+		"sideEffects": ["*.css"],
+		"dependencies": {"a": "^1.0.0"},
+		"peerDependencies": {"b": "*"},
+		"optionalDependencies": {"c": "2"},
+		"workspaces": {"packages": ["packages/*"]},
+		"custom": 1
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fields := []struct {
+		field string
+		got   string
+		want  string
+	}{
+		{"name", m.Name(), "pkg"},
+		{"version", m.Version(), "1.2.3"},
+		{"type", m.Type(), "module"},
+		{"main", m.Main(), "./main.cjs"},
+		{"module", m.Module(), "./main.mjs"},
+		{"types", m.Types(), "./main.d.ts"},
+	}
+	for _, tt := range fields {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+
+	browserMain, replacements := m.Browser()
+	if browserMain != "" || !reflect.DeepEqual(replacements, map[string]string{"./main.cjs": "./browser.js", "fs": ""}) {
+		t.Errorf("Browser() = (%q, %v)", browserMain, replacements)
+	}
+	if _, ok := m.Exports(); !ok {
+		t.Errorf("Exports() not found")
+	}
+	if _, ok := m.Imports(); ok {
+		t.Errorf("Imports() found for null field")
+	}
+	if got, want := m.TypesVersions(), map[string]map[string][]string{">=4": {"*": {"ts4/*"}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TypesVersions() = %v, want %v", got, want)
+	}
+	if all, patterns := m.SideEffects(); all || !reflect.DeepEqual(patterns, []string{"*.css"}) {
+		t.Errorf("SideEffects() = (%v, %v)", all, patterns)
+	}
+	if got := m.Dependencies(); !reflect.DeepEqual(got, map[string]string{"a": "^1.0.0"}) {
+		t.Errorf("Dependencies() = %v", got)
+	}
+	if got := m.PeerDependencies(); !reflect.DeepEqual(got, map[string]string{"b": "*"}) {
+		t.Errorf("PeerDependencies() = %v", got)
+	}
+	if got := m.OptionalDependencies(); !reflect.DeepEqual(got, map[string]string{"c": "2"}) {
+		t.Errorf("OptionalDependencies() = %v", got)
+	}
+	if got := m.Workspaces(); !reflect.DeepEqual(got, []string{"packages/*"}) {
+		t.Errorf("Workspaces() = %v", got)
+	}
+	if v, ok := m.Field("custom"); !ok || v != float64(1) {
+		t.Errorf("Field(custom) = (%v, %v)", v, ok)
+	}
+}
+
+func TestManifestDefaults(t *testing.T) {
+	m, err := ParseManifest([]byte(`{"sideEffects": false, "workspaces": ["a", "b"]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if all, patterns := m.SideEffects(); all || patterns != nil {
+		t.Errorf("SideEffects() = (%v, %v), want (false, nil)", all, patterns)
+	}
+	if got := m.Workspaces(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Workspaces() = %v", got)
+	}
+
+	empty, _ := ParseManifest([]byte(`{}`))
+	if all, _ := empty.SideEffects(); !all {
+		t.Errorf("SideEffects() on empty manifest = false, want true")
+	}
+	if empty.Dependencies() != nil {
+		t.Errorf("Dependencies() on empty manifest = %v, want nil", empty.Dependencies())
+	}
+}
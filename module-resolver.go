@@ -1,17 +1,25 @@
 package resolve
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"io/fs"
+	"maps"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 )
 
+// ModuleResolver is safe for concurrent use, provided its Config is not modified
+// meanwhile and its FS and IsCoreModule are safe for concurrent use.
 type ModuleResolver struct {
-	Config *ResolverConfig
+	Config    *ResolverConfig
+	manifests sync.Map
+	scopes    sync.Map
+	inflight  sync.Map
 }
 
 type FS interface {
@@ -19,6 +27,21 @@ type FS interface {
 	ReadFile(path string) ([]byte, error)
 }
 
+// ReadDirFS is implemented by an FS that can list directories, which is needed to
+// expand exports patterns.
+type ReadDirFS interface {
+	FS
+	ReadDir(path string) ([]fs.DirEntry, error)
+}
+
+// ContextFS is implemented by an FS whose operations can be canceled, such as one
+// backed by a network filesystem. The Context methods of ModuleResolver use it.
+type ContextFS interface {
+	FS
+	StatContext(ctx context.Context, path string) (fs.FileInfo, error)
+	ReadFileContext(ctx context.Context, path string) ([]byte, error)
+}
+
 type Path interface {
 	Dir(path string) string
 	Join(elem ...string) string
@@ -46,26 +69,56 @@ func (*osFS) ReadFile(path string) ([]byte, error) {
 	return os.ReadFile(path)
 }
 
+func (*osFS) ReadDir(path string) ([]fs.DirEntry, error) {
+	return os.ReadDir(path)
+}
+
 type ResolverConfig struct {
-	Extensions           []string
-	ExtensionMap         map[string][]string
+	Extensions   []string
+	ExtensionMap map[string][]string
+	// ExtensionMapFirst probes ExtensionMap substitutions before the exact path.
+	ExtensionMapFirst    bool
 	IsCoreModule         func(string) bool
 	ModulesDirectoryName string
 	ManifestFileName     string
 	MainFields           []string
-	IndexName            string
-	Conditions           []string
-	FS                   FS
-	Path                 Path
+	// MainFieldConditions restricts a main field to resolutions where at least one
+	// of its conditions is active, e.g. "module": {"import"}, "browser": {"browser"}.
+	MainFieldConditions map[string][]string
+	IndexName           string
+	// IndexNames are the directory index files tried in order. It defaults to IndexName.
+	IndexNames []string
+	// DisableDirectoryIndex turns off index probing, as in strict ESM resolution.
+	DisableDirectoryIndex bool
+	Conditions            []string
+	// ProbeFallbacks tries every target of exports and imports array fallbacks on
+	// disk. By default only the first valid target is used, as in Node.
+	ProbeFallbacks bool
+	// AdditionalModulePaths are searched after the node_modules hierarchy,
+	// in order, like NODE_PATH and the global folders in CommonJS.
+	AdditionalModulePaths []string
+	// Concurrency bounds the workers of ResolveMany. It defaults to GOMAXPROCS.
+	Concurrency int
+	FS          FS
+	Path        Path
 }
 
-func NewModuleResolver(config *ResolverConfig) *ModuleResolver {
+// NewModuleResolver returns a resolver for a copy of config with defaults filled in;
+// config itself is not modified.
+func NewModuleResolver(c *ResolverConfig) *ModuleResolver {
+	config := c.clone()
 	if config.ModulesDirectoryName == "" {
 		config.ModulesDirectoryName = "node_modules"
 	}
 	if config.ManifestFileName == "" {
 		config.ManifestFileName = "package.json"
 	}
+	if config.IndexName == "" {
+		config.IndexName = "index"
+	}
+	if len(config.IndexNames) == 0 {
+		config.IndexNames = []string{config.IndexName}
+	}
 	if len(config.MainFields) == 0 {
 		config.MainFields = []string{"main"}
 	}
@@ -86,6 +139,29 @@ func NewModuleResolver(config *ResolverConfig) *ModuleResolver {
 	}
 }
 
+func (c *ResolverConfig) clone() *ResolverConfig {
+	config := *c
+	config.Extensions = slices.Clone(c.Extensions)
+	config.ExtensionMap = cloneLists(c.ExtensionMap)
+	config.MainFields = slices.Clone(c.MainFields)
+	config.MainFieldConditions = cloneLists(c.MainFieldConditions)
+	config.IndexNames = slices.Clone(c.IndexNames)
+	config.Conditions = slices.Clone(c.Conditions)
+	config.AdditionalModulePaths = slices.Clone(c.AdditionalModulePaths)
+	return &config
+}
+
+func cloneLists(m map[string][]string) map[string][]string {
+	if m == nil {
+		return nil
+	}
+	result := make(map[string][]string, len(m))
+	for key, list := range m {
+		result[key] = slices.Clone(list)
+	}
+	return result
+}
+
 func (r *ModuleResolver) ModulesPaths(start string, name string) []string {
 	var paths []string
 
@@ -105,31 +181,87 @@ func (r *ModuleResolver) ModulesPaths(start string, name string) []string {
 	return paths
 }
 
-func (r *ModuleResolver) stat(path string) (fs.FileInfo, error) {
+// GlobalModulePaths returns the CommonJS global folders derived from env:
+// the NODE_PATH entries, $HOME/.node_modules, $HOME/.node_libraries and $PREFIX/lib/node.
+func GlobalModulePaths(env map[string]string) []string {
+	var paths []string
+
+	for _, p := range filepath.SplitList(env["NODE_PATH"]) {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+
+	home := env["HOME"]
+	if home == "" {
+		home = env["USERPROFILE"]
+	}
+	if home != "" {
+		paths = append(paths, filepath.Join(home, ".node_modules"), filepath.Join(home, ".node_libraries"))
+	}
+	if prefix := env["PREFIX"]; prefix != "" {
+		paths = append(paths, filepath.Join(prefix, "lib", "node"))
+	}
+
+	return paths
+}
+
+func (r *ModuleResolver) stat(ctx context.Context, path string) (fs.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if contextFS, ok := r.Config.FS.(ContextFS); ok {
+		return contextFS.StatContext(ctx, path)
+	}
 	return r.Config.FS.Stat(path)
 }
 
-func (r *ModuleResolver) resolveFile(filePath string) string {
-	candidates := map[string]struct{}{
-		filePath: {},
+func (r *ModuleResolver) readFile(ctx context.Context, path string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if contextFS, ok := r.Config.FS.(ContextFS); ok {
+		return contextFS.ReadFileContext(ctx, path)
+	}
+	return r.Config.FS.ReadFile(path)
+}
+
+// FileCandidates returns the files probed for filePath, in order: the exact path,
+// its ExtensionMap substitutions, then filePath with each of Extensions appended.
+// With ExtensionMapFirst the substitutions come before the exact path.
+func (r *ModuleResolver) FileCandidates(filePath string) []string {
+	var candidates []string
+	seen := make(map[string]struct{})
+	add := func(file string) {
+		if _, ok := seen[file]; ok {
+			return
+		}
+		seen[file] = struct{}{}
+		candidates = append(candidates, file)
+	}
+
+	if !r.Config.ExtensionMapFirst {
+		add(filePath)
 	}
 	filePathExt := path.Ext(filePath)
 	if exts, ok := r.Config.ExtensionMap[filePathExt]; ok {
 		base := filePath[:len(filePath)-len(filePathExt)]
 		for _, ext := range exts {
-			candidates[base+ext] = struct{}{}
+			add(base + ext)
 		}
 	}
-
+	add(filePath)
 	for _, ext := range r.Config.Extensions {
-		candidates[filePath+ext] = struct{}{}
+		add(filePath + ext)
 	}
-	for file := range candidates {
-		stat, err := r.stat(file)
-		if err != nil || stat.IsDir() {
-			continue
-		}
-		if !stat.IsDir() {
+
+	return candidates
+}
+
+func (r *ModuleResolver) resolveFile(ctx context.Context, filePath string) string {
+	for _, file := range r.FileCandidates(filePath) {
+		stat, err := r.stat(ctx, file)
+		if err == nil && !stat.IsDir() {
 			return file
 		}
 	}
@@ -137,28 +269,30 @@ func (r *ModuleResolver) resolveFile(filePath string) string {
 	return ""
 }
 
-func (r *ModuleResolver) resolveDir(dirPath string, entry string) string {
-	packageJSONPath := r.Config.Path.Join(dirPath, r.Config.ManifestFileName)
-	stat, err := r.stat(packageJSONPath)
+func (r *ModuleResolver) resolveDir(ctx context.Context, dirPath string, entry string) string {
+	manifestPath := r.Config.Path.Join(dirPath, r.Config.ManifestFileName)
+	stat, err := r.stat(ctx, manifestPath)
 	if err != nil || stat.IsDir() {
-		return r.resolveFile(r.Config.Path.Join(dirPath, r.Config.IndexName))
+		return r.resolveIndex(ctx, dirPath)
 	}
 
-	pkg, err := r.readJSON(packageJSONPath)
+	manifest, err := r.readManifest(ctx, manifestPath)
 	if err != nil {
-		return r.resolveFile(r.Config.Path.Join(dirPath, r.Config.IndexName))
+		return r.resolveIndex(ctx, dirPath)
 	}
 
-	if exports, ok := pkg["exports"]; ok {
+	if exports, ok := manifest.Exports(); ok {
 		exportsResolver := NewSubpathResolver(SubpathResolverConfig{
-			Exports:    exports,
-			Conditions: r.Config.Conditions,
+			Exports:        exports,
+			Conditions:     r.Config.Conditions,
+			ProbeFallbacks: r.Config.ProbeFallbacks,
 		})
 		exportsMatchArray := exportsResolver.ResolveExports(entry)
 
+		// Exports targets name exact files: no extension, index or manifest probing.
 		for _, match := range exportsMatchArray {
 			matchPath := r.Config.Path.Join(dirPath, match)
-			stat, err := r.stat(matchPath)
+			stat, err := r.stat(ctx, matchPath)
 			if err == nil && !stat.IsDir() {
 				return matchPath
 			}
@@ -168,94 +302,259 @@ func (r *ModuleResolver) resolveDir(dirPath string, entry string) string {
 	}
 
 	if entry == "" {
-		for _, field := range r.Config.MainFields {
-			if main, ok := pkg[field].(string); ok && main != "" {
-				mainPath := r.Config.Path.Join(dirPath, main)
-				stat, err := r.stat(mainPath)
-				if err == nil && !stat.IsDir() {
-					return mainPath
+		return r.resolveMain(ctx, dirPath, manifest)
+	}
+
+	subPath := r.Config.Path.Join(dirPath, entry)
+	return r.resolveFileOrDir(ctx, subPath, "")
+}
+
+// resolveMain applies extension and index probing to each active main field in
+// order, like LOAD_AS_DIRECTORY, then falls back to the directory index.
+func (r *ModuleResolver) resolveMain(ctx context.Context, dirPath string, manifest *Manifest) string {
+	for _, field := range r.Config.MainFields {
+		if !r.mainFieldActive(field) {
+			continue
+		}
+		main := mainFieldValue(manifest, field)
+		if main == "" {
+			continue
+		}
+		mainPath := r.Config.Path.Join(dirPath, main)
+		if file := r.resolveFile(ctx, mainPath); file != "" {
+			return file
+		}
+		if file := r.resolveIndex(ctx, mainPath); file != "" {
+			return file
+		}
+	}
+	return r.resolveIndex(ctx, dirPath)
+}
+
+func (r *ModuleResolver) mainFieldActive(field string) bool {
+	conditions, ok := r.Config.MainFieldConditions[field]
+	if !ok {
+		return true
+	}
+	for _, cond := range conditions {
+		if slices.Contains(r.Config.Conditions, cond) {
+			return true
+		}
+	}
+	return false
+}
+
+// mainFieldValue returns the main path declared by field. An object value, like the
+// browser field, maps package files to replacements and applies to the main file.
+func mainFieldValue(manifest *Manifest, field string) string {
+	switch v := manifest.Raw[field].(type) {
+	case string:
+		return v
+	case map[string]any:
+		main := manifest.Main()
+		if main == "" {
+			main = "index"
+		}
+		main = path.Clean(main)
+		keys := slices.Sorted(maps.Keys(v))
+		for _, candidate := range []string{main, main + ".js", strings.TrimSuffix(main, ".js")} {
+			for _, key := range keys {
+				if path.Clean(key) == candidate {
+					replacement, _ := v[key].(string)
+					return replacement
 				}
 			}
 		}
-		return ""
 	}
+	return ""
+}
 
-	subPath := r.Config.Path.Join(dirPath, entry)
-	return r.resolveFileOrDir(subPath, entry)
+func (r *ModuleResolver) resolveIndex(ctx context.Context, dirPath string) string {
+	if r.Config.DisableDirectoryIndex {
+		return ""
+	}
+	for _, name := range r.Config.IndexNames {
+		if file := r.resolveFile(ctx, r.Config.Path.Join(dirPath, name)); file != "" {
+			return file
+		}
+	}
+	return ""
 }
 
-func (r *ModuleResolver) resolveFileOrDir(subPath string, entry string) string {
-	if file := r.resolveFile(subPath); file != "" {
+func (r *ModuleResolver) resolveFileOrDir(ctx context.Context, subPath string, entry string) string {
+	if file := r.resolveFile(ctx, subPath); file != "" {
 		return file
 	}
-	return r.resolveDir(subPath, entry)
+	return r.resolveDir(ctx, subPath, entry)
 }
 
-func (r *ModuleResolver) readJSON(path string) (map[string]any, error) {
-	data, err := r.Config.FS.ReadFile(path)
-	if err != nil {
-		return nil, err
+func (r *ModuleResolver) FindManifest(base string) (*Manifest, error) {
+	manifest, _, err := r.FindPackageScope(base)
+	return manifest, err
+}
+
+type packageScope struct {
+	manifest *Manifest
+	dir      string
+	err      error
+}
+
+// FindPackageScope returns the nearest manifest at or above base and the directory
+// holding it, like Node's LOOKUP_PACKAGE_SCOPE. The search stops at the first
+// modules directory, returning ErrPackageScopeNotFound.
+func (r *ModuleResolver) FindPackageScope(base string) (*Manifest, string, error) {
+	return r.packageScope(context.Background(), base)
+}
+
+func (r *ModuleResolver) packageScope(ctx context.Context, base string) (*Manifest, string, error) {
+	if cached, ok := r.scopes.Load(base); ok {
+		scope := cached.(*packageScope)
+		return scope.manifest, scope.dir, scope.err
+	}
+	manifest, dir, err := r.findPackageScope(ctx, base)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, "", ctxErr
 	}
+	if unreadManifest(err) {
+		return nil, "", err
+	}
+	r.scopes.Store(base, &packageScope{manifest: manifest, dir: dir, err: err})
+	return manifest, dir, err
+}
 
-	var result map[string]any
-	if err := json.Unmarshal(data, &result); err != nil {
-		return nil, err
+// ClearCache drops every cached manifest and package scope lookup.
+func (r *ModuleResolver) ClearCache() {
+	r.manifests.Clear()
+	r.scopes.Clear()
+}
+
+func (r *ModuleResolver) findPackageScope(ctx context.Context, base string) (*Manifest, string, error) {
+	dir := base
+	for {
+		if r.baseName(dir) == r.Config.ModulesDirectoryName {
+			break
+		}
+
+		manifestPath := r.Config.Path.Join(dir, r.Config.ManifestFileName)
+		stat, err := r.stat(ctx, manifestPath)
+		if err == nil && !stat.IsDir() {
+			manifest, err := r.readManifest(ctx, manifestPath)
+			if err != nil {
+				return nil, "", err
+			}
+			return manifest, dir, nil
+		}
+
+		parent := r.Config.Path.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
 	}
 
-	return result, nil
+	return nil, "", ErrPackageScopeNotFound
 }
 
-func (r *ModuleResolver) FindManifest(base string) (map[string]any, any) {
-	p, err := r.FindUp(base, r.Config.ManifestFileName)
-	if err != nil {
-		return nil, err
+type manifestResult struct {
+	manifest *Manifest
+	err      error
+}
+
+// readManifest reads and parses the manifest at path, caching the outcome unless
+// the file could not be read, which may succeed on a retry.
+func (r *ModuleResolver) readManifest(ctx context.Context, path string) (*Manifest, error) {
+	if cached, ok := r.manifests.Load(path); ok {
+		result := cached.(*manifestResult)
+		return result.manifest, result.err
+	}
+	data, err := r.readFile(ctx, path)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
 	}
-	content, err := os.ReadFile(p)
 	if err != nil {
-		return nil, err
+		return nil, &ManifestError{Path: path, Err: err, unread: true}
 	}
-	var manifest map[string]any
-	err = json.Unmarshal(content, &manifest)
+	manifest, err := ParseManifest(data)
 	if err != nil {
-		return nil, err
+		err = &ManifestError{Path: path, Err: err}
 	}
+	r.manifests.Store(path, &manifestResult{manifest: manifest, err: err})
 	return manifest, err
 }
 
+// unreadManifest reports whether err is a manifest that could not be read.
+func unreadManifest(err error) bool {
+	var manifestErr *ManifestError
+	return errors.As(err, &manifestErr) && manifestErr.unread
+}
+
+func (r *ModuleResolver) baseName(p string) string {
+	return strings.TrimLeft(strings.TrimPrefix(p, r.Config.Path.Dir(p)), `/\`)
+}
+
 func (r *ModuleResolver) Resolve(path string, base string) string {
+	return r.resolve(context.Background(), path, base)
+}
+
+// ResolveContext is like Resolve but checks ctx between filesystem operations,
+// returning its error once it is done.
+func (r *ModuleResolver) ResolveContext(ctx context.Context, path string, base string) (string, error) {
+	return contextResult(ctx, r.resolve(ctx, path, base))
+}
+
+func contextResult(ctx context.Context, resolved string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+func (r *ModuleResolver) resolve(ctx context.Context, path string, base string) string {
 	if strings.HasPrefix(path, "#") {
-		return r.ResolveImports(path, base)
+		return r.resolveImports(ctx, path, base)
 	}
 	spec, err := NewSpecifier(path)
 	if err == nil && spec.Name != "" {
-		if r.Config.IsCoreModule(spec.Name) {
+		if r.isCoreModule(path, spec) {
 			return path
 		}
 
-		return r.ResolveModuleSpecifier(spec, base)
+		return r.resolveModuleSpecifier(ctx, spec, base)
 	}
 
-	return r.resolveFileOrDir(r.Config.Path.Join(base, path), "")
+	return r.resolveFileOrDir(ctx, r.Config.Path.Join(base, path), "")
+}
+
+func (r *ModuleResolver) isCoreModule(path string, spec *Specifier) bool {
+	return r.Config.IsCoreModule(path) || r.Config.IsCoreModule(spec.Name)
 }
 
+// ResolveImports resolves a "#" specifier through the imports field of the package
+// scope of base. Relative targets are resolved against the directory of the manifest
+// declaring them, bare targets as package specifiers from that directory.
 func (r *ModuleResolver) ResolveImports(path, base string) string {
-	manifest, err := r.FindManifest(base)
+	return r.resolveImports(context.Background(), path, base)
+}
+
+// ResolveImportsContext is like ResolveImports but checks ctx between filesystem
+// operations, returning its error once it is done.
+func (r *ModuleResolver) ResolveImportsContext(ctx context.Context, path, base string) (string, error) {
+	return contextResult(ctx, r.resolveImports(ctx, path, base))
+}
+
+func (r *ModuleResolver) resolveImports(ctx context.Context, path, base string) string {
+	manifest, dir, err := r.packageScope(ctx, base)
 	if err != nil {
 		return ""
 	}
-	if imports, ok := manifest["imports"]; ok {
+	if imports, ok := manifest.Imports(); ok {
 		subpathResolver := NewSubpathResolver(SubpathResolverConfig{
-			Imports:    imports,
-			Conditions: r.Config.Conditions,
+			Imports:        imports,
+			Conditions:     r.Config.Conditions,
+			ProbeFallbacks: r.Config.ProbeFallbacks,
 		})
-		subpathResolved := subpathResolver.ResolveImports(path)
-		for _, file := range subpathResolved {
-			file = r.Config.Path.Join(base, file)
-			stat, err := r.stat(file)
-			if err != nil || stat.IsDir() {
-				continue
-			}
-			if !stat.IsDir() {
+		for _, target := range subpathResolver.ResolveImports(path) {
+			if file := r.resolveImportsTarget(ctx, dir, target); file != "" {
 				return file
 			}
 		}
@@ -263,30 +562,124 @@ func (r *ModuleResolver) ResolveImports(path, base string) string {
 	return ""
 }
 
+func (r *ModuleResolver) resolveImportsTarget(ctx context.Context, dir, target string) string {
+	if isRelativeTarget(target) {
+		return r.resolveFileOrDir(ctx, r.Config.Path.Join(dir, target), "")
+	}
+	spec, err := NewSpecifier(target)
+	if err != nil {
+		return ""
+	}
+	if r.isCoreModule(target, spec) {
+		return target
+	}
+	return r.resolveModuleSpecifier(ctx, spec, dir)
+}
+
+func isRelativeTarget(target string) bool {
+	return strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../")
+}
+
 func (r *ModuleResolver) ResolveModuleSpecifier(spec *Specifier, base string) string {
+	return r.resolveModuleSpecifier(context.Background(), spec, base)
+}
+
+// ResolveModuleSpecifierContext is like ResolveModuleSpecifier but checks ctx between
+// filesystem operations, returning its error once it is done.
+func (r *ModuleResolver) ResolveModuleSpecifierContext(ctx context.Context, spec *Specifier, base string) (string, error) {
+	return contextResult(ctx, r.resolveModuleSpecifier(ctx, spec, base))
+}
+
+func (r *ModuleResolver) resolveModuleSpecifier(ctx context.Context, spec *Specifier, base string) string {
 	dirs := r.ModulesPaths(base, spec.Name)
 	for _, dir := range dirs {
-		stat, err := r.Config.FS.Stat(dir)
-		if err == nil && stat.IsDir() {
-			rd := r.resolveDir(dir, spec.Path)
-			if rd != "" {
-				return rd
-			}
+		if rd := r.resolvePackage(ctx, dir, spec.Path); rd != "" {
+			return rd
+		}
+	}
+	return r.resolveAdditional(ctx, spec)
+}
+
+func (r *ModuleResolver) resolveAdditional(ctx context.Context, spec *Specifier) string {
+	for _, p := range r.Config.AdditionalModulePaths {
+		if rd := r.resolvePackage(ctx, r.Config.Path.Join(p, spec.Name), spec.Path); rd != "" {
+			return rd
 		}
 	}
 	return ""
 }
 
+func (r *ModuleResolver) resolvePackage(ctx context.Context, dir string, entry string) string {
+	stat, err := r.stat(ctx, dir)
+	if err != nil || !stat.IsDir() {
+		return ""
+	}
+	return r.resolveDir(ctx, dir, entry)
+}
+
+// ResolveFrom resolves path as if it were required from each of roots in turn,
+// like require.resolve with the paths option. Bare specifiers are looked up in the
+// union of the roots' ModulesPaths. It returns the resolved file and the root that
+// produced it; root is empty for core modules and AdditionalModulePaths hits.
+func (r *ModuleResolver) ResolveFrom(path string, roots []string) (resolved string, root string) {
+	ctx := context.Background()
+	if strings.HasPrefix(path, "#") {
+		for _, root := range roots {
+			if resolved := r.resolveImports(ctx, path, root); resolved != "" {
+				return resolved, root
+			}
+		}
+		return "", ""
+	}
+
+	spec, err := NewSpecifier(path)
+	if err == nil && spec.Name != "" {
+		if r.isCoreModule(path, spec) {
+			return path, ""
+		}
+
+		seen := make(map[string]struct{})
+		for _, root := range roots {
+			for _, dir := range r.ModulesPaths(root, spec.Name) {
+				if _, ok := seen[dir]; ok {
+					continue
+				}
+				seen[dir] = struct{}{}
+				if resolved := r.resolvePackage(ctx, dir, spec.Path); resolved != "" {
+					return resolved, root
+				}
+			}
+		}
+		return r.resolveAdditional(ctx, spec), ""
+	}
+
+	for _, root := range roots {
+		if resolved := r.resolveFileOrDir(ctx, r.Config.Path.Join(root, path), ""); resolved != "" {
+			return resolved, root
+		}
+	}
+	return "", ""
+}
+
 var ErrNoUpwardsFound = errors.New("err no upwards found")
 
 func (r *ModuleResolver) FindUp(startDir, target string) (string, error) {
+	return r.FindUpContext(context.Background(), startDir, target)
+}
+
+// FindUpContext is like FindUp but checks ctx between filesystem operations,
+// returning its error once it is done.
+func (r *ModuleResolver) FindUpContext(ctx context.Context, startDir, target string) (string, error) {
 	dir := startDir
 	filepath := r.Config.Path
 	for {
 		candidate := filepath.Join(dir, target)
-		if _, err := r.stat(candidate); err == nil {
+		if _, err := r.stat(ctx, candidate); err == nil {
 			return candidate, nil
 		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 
 		parent := filepath.Dir(dir)
 		if parent == dir {
@@ -0,0 +1,647 @@
+package resolve
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+type memFS fstest.MapFS
+
+func (m memFS) Stat(name string) (fs.FileInfo, error) {
+	return fstest.MapFS(m).Stat(strings.TrimPrefix(name, "/"))
+}
+
+func (m memFS) ReadFile(name string) ([]byte, error) {
+	return fstest.MapFS(m).ReadFile(strings.TrimPrefix(name, "/"))
+}
+
+func (m memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fstest.MapFS(m).ReadDir(strings.TrimPrefix(name, "/"))
+}
+
+type posixPath struct{}
+
+func (posixPath) Dir(p string) string { return path.Dir(p) }
+
+func (posixPath) Join(elem ...string) string { return path.Join(elem...) }
+
+func newMemResolver(files map[string]string, config ResolverConfig) *ModuleResolver {
+	m := memFS{}
+	for name, content := range files {
+		m[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	config.FS = m
+	config.Path = posixPath{}
+	return NewModuleResolver(&config)
+}
+
+func TestGlobalModulePaths(t *testing.T) {
+	env := map[string]string{
+		"NODE_PATH": filepath.Join("/a") + string(os.PathListSeparator) + filepath.Join("/b"),
+		"HOME":      filepath.Join("/home/user"),
+		"PREFIX":    filepath.Join("/usr/local"),
+	}
+	want := []string{
+		filepath.Join("/a"),
+		filepath.Join("/b"),
+		filepath.Join("/home/user", ".node_modules"),
+		filepath.Join("/home/user", ".node_libraries"),
+		filepath.Join("/usr/local", "lib", "node"),
+	}
+	if got := GlobalModulePaths(env); !reflect.DeepEqual(got, want) {
+		t.Errorf("GlobalModulePaths() = %v, want %v", got, want)
+	}
+	if got := GlobalModulePaths(nil); got != nil {
+		t.Errorf("GlobalModulePaths(nil) = %v, want nil", got)
+	}
+}
+
+func TestAdditionalModulePaths(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"app/index.js":                        "",
+		"global/legacy/index.js":              "",
+		"global/shadowed/index.js":            "",
+		"app/node_modules/shadowed/index.js":  "",
+		"app/node_modules/shadowed/README.md": "",
+	})
+	r := NewModuleResolver(&ResolverConfig{
+		Extensions:            []string{".js"},
+		IndexName:             "index",
+		AdditionalModulePaths: []string{filepath.Join(root, "global")},
+	})
+	base := filepath.Join(root, "app")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"legacy", filepath.Join(root, "global", "legacy", "index.js")},
+		{"shadowed", filepath.Join(root, "app", "node_modules", "shadowed", "index.js")},
+		{"missing", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := r.Resolve(tt.input, base); got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveFrom(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"packages/web/node_modules/react/index.js":   "",
+		"packages/web/src/util.js":                   "",
+		"packages/api/node_modules/express/index.js": "",
+		"node_modules/shared/index.js":               "",
+	})
+	r := NewModuleResolver(&ResolverConfig{
+		Extensions: []string{".js"},
+		IndexName:  "index",
+	})
+	web := filepath.Join(root, "packages", "web")
+	api := filepath.Join(root, "packages", "api")
+	roots := []string{web, api}
+
+	tests := []struct {
+		input    string
+		want     string
+		wantRoot string
+	}{
+		{"react", filepath.Join(web, "node_modules", "react", "index.js"), web},
+		{"express", filepath.Join(api, "node_modules", "express", "index.js"), api},
+		{"shared", filepath.Join(root, "node_modules", "shared", "index.js"), web},
+		{"./src/util", filepath.Join(web, "src", "util.js"), web},
+		{"node:fs", "node:fs", ""},
+		{"missing", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, gotRoot := r.ResolveFrom(tt.input, roots)
+			if got != tt.want || gotRoot != tt.wantRoot {
+				t.Errorf("ResolveFrom(%q) = (%q, %q), want (%q, %q)", tt.input, got, gotRoot, tt.want, tt.wantRoot)
+			}
+		})
+	}
+}
+
+func TestFindPackageScope(t *testing.T) {
+	r := newMemResolver(map[string]string{
+		"app/package.json":                   `{"name": "app", "type": "module"}`,
+		"app/node_modules/dep/lib/index.js":  "",
+		"app/node_modules/dep/package.json":  `{"name": "dep"}`,
+		"app/node_modules/bare/lib/index.js": "",
+		"broken/package.json":                `{`,
+	}, ResolverConfig{})
+
+	tests := []struct {
+		base    string
+		name    string
+		dir     string
+		wantErr error
+	}{
+		{"/app/src/deep", "app", "/app", nil},
+		{"/app", "app", "/app", nil},
+		{"/app/node_modules/dep/lib", "dep", "/app/node_modules/dep", nil},
+		{"/app/node_modules/bare/lib", "", "", ErrPackageScopeNotFound},
+		{"/elsewhere", "", "", ErrPackageScopeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.base, func(t *testing.T) {
+			manifest, dir, err := r.FindPackageScope(tt.base)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FindPackageScope(%q) error = %v, want %v", tt.base, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if manifest.Name() != tt.name || dir != tt.dir {
+				t.Errorf("FindPackageScope(%q) = (%q, %q), want (%q, %q)", tt.base, manifest.Name(), dir, tt.name, tt.dir)
+			}
+		})
+	}
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		_, _, err := r.FindPackageScope("/broken/src")
+		var manifestErr *ManifestError
+		if !errors.As(err, &manifestErr) || manifestErr.Path != "/broken/package.json" {
+			t.Errorf("FindPackageScope() error = %v, want ManifestError for /broken/package.json", err)
+		}
+	})
+}
+
+// flakyFS fails the first read of every file, then reads through.
+type flakyFS struct {
+	memFS
+	mu    sync.Mutex
+	reads map[string]int
+}
+
+func (f *flakyFS) ReadFile(name string) ([]byte, error) {
+	f.mu.Lock()
+	f.reads[name]++
+	first := f.reads[name] == 1
+	f.mu.Unlock()
+	if first {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("transient failure")}
+	}
+	return f.memFS.ReadFile(name)
+}
+
+func TestReadManifestErrors(t *testing.T) {
+	files := &flakyFS{
+		memFS: memFS{
+			"app/package.json":    &fstest.MapFile{Data: []byte(`{"name": "app"}`)},
+			"broken/package.json": &fstest.MapFile{Data: []byte(`{`)},
+		},
+		reads: make(map[string]int),
+	}
+	r := NewModuleResolver(&ResolverConfig{FS: files, Path: posixPath{}})
+
+	if _, _, err := r.FindPackageScope("/app"); err == nil {
+		t.Fatal("FindPackageScope() succeeded despite a failed read")
+	}
+	manifest, _, err := r.FindPackageScope("/app")
+	if err != nil || manifest.Name() != "app" {
+		t.Errorf("FindPackageScope() after a failed read = %v, %v; want app", manifest, err)
+	}
+
+	for range 3 {
+		var manifestErr *ManifestError
+		if _, _, err := r.FindPackageScope("/broken"); !errors.As(err, &manifestErr) {
+			t.Fatalf("FindPackageScope() error = %v, want ManifestError", err)
+		}
+	}
+	if reads := files.reads["/broken/package.json"]; reads != 2 {
+		t.Errorf("invalid manifest read %d times, want 2", reads)
+	}
+}
+
+func TestResolveImports(t *testing.T) {
+	r := newMemResolver(map[string]string{
+		"app/package.json": `{
+			"imports": {
+				"#utils": "./src/utils/index.js",
+				"#lib/*": "./src/lib/*",
+				"#dep": "dep/sub/file.js",
+				"#fs": "node:fs",
+				"#missing": "./nope.js"
+			}
+		}`,
+		"app/src/utils/index.js":            "",
+		"app/src/lib/math.ts":               "",
+		"app/src/deep/nested/file.js":       "",
+		"app/node_modules/dep/sub/file.js":  "",
+		"app/node_modules/dep/package.json": `{"name": "dep"}`,
+	}, ResolverConfig{
+		Extensions: []string{".ts", ".js"},
+		IndexName:  "index",
+	})
+	base := "/app/src/deep/nested"
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"#utils", "/app/src/utils/index.js"},
+		{"#lib/math", "/app/src/lib/math.ts"},
+		{"#dep", "/app/node_modules/dep/sub/file.js"},
+		{"#fs", "node:fs"},
+		{"#missing", ""},
+		{"#undefined", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := r.Resolve(tt.input, base); got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveExportsExactTargets(t *testing.T) {
+	files := map[string]string{
+		"node_modules/dir/package.json":      `{"exports": {".": "./lib"}}`,
+		"node_modules/dir/lib/index.js":      "",
+		"node_modules/ext/package.json":      `{"exports": {"./foo": "./foo", "./bar": "./bar.js"}}`,
+		"node_modules/ext/foo.js":            "",
+		"node_modules/ext/bar.js":            "",
+		"node_modules/main/package.json":     `{"exports": "./sub"}`,
+		"node_modules/main/sub/package.json": `{"main": "./other.js"}`,
+		"node_modules/main/sub/other.js":     "",
+	}
+	r := newMemResolver(files, ResolverConfig{Extensions: []string{".js"}})
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"dir", ""},
+		{"ext/foo", ""},
+		{"ext/bar", "/node_modules/ext/bar.js"},
+		{"main", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := r.Resolve(tt.input, "/"); got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveConditionOrder(t *testing.T) {
+	files := map[string]string{
+		"package.json":                     `{"imports": {"#dep": {"node": "./node.js", "default": "./dep.js"}}}`,
+		"node.js":                          "",
+		"dep.js":                           "",
+		"node_modules/first/package.json":  `{"exports": {"default": "./a.js", "import": "./b.js"}}`,
+		"node_modules/first/a.js":          "",
+		"node_modules/first/b.js":          "",
+		"node_modules/module/package.json": `{"exports": {".": {"import": "./b.mjs", "require": "./b.cjs"}}}`,
+		"node_modules/module/b.mjs":        "",
+		"node_modules/module/b.cjs":        "",
+	}
+	r := newMemResolver(files, ResolverConfig{Conditions: []string{"require", "default", "import", "node"}})
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"first", "/node_modules/first/a.js"},
+		{"module", "/node_modules/module/b.mjs"},
+		{"#dep", "/node.js"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := r.Resolve(tt.input, "/"); got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileCandidates(t *testing.T) {
+	config := ResolverConfig{
+		Extensions: []string{".ts", ".js"},
+		ExtensionMap: map[string][]string{
+			".js": {".ts", ".tsx", ".js"},
+		},
+	}
+
+	tests := []struct {
+		name              string
+		input             string
+		extensionMapFirst bool
+		want              []string
+	}{
+		{
+			name:  "exact first",
+			input: "/src/foo.js",
+			want:  []string{"/src/foo.js", "/src/foo.ts", "/src/foo.tsx", "/src/foo.js.ts", "/src/foo.js.js"},
+		},
+		{
+			name:              "extension map first",
+			input:             "/src/foo.js",
+			extensionMapFirst: true,
+			want:              []string{"/src/foo.ts", "/src/foo.tsx", "/src/foo.js", "/src/foo.js.ts", "/src/foo.js.js"},
+		},
+		{
+			name:  "no mapped extension",
+			input: "/src/foo",
+			want:  []string{"/src/foo", "/src/foo.ts", "/src/foo.js"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := config
+			config.ExtensionMapFirst = tt.extensionMapFirst
+			r := newMemResolver(nil, config)
+			if got := r.FileCandidates(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FileCandidates(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveFileOrder(t *testing.T) {
+	files := map[string]string{
+		"src/foo.js": "",
+		"src/foo.ts": "",
+		"src/bar.ts": "",
+		"src/bar.js": "",
+	}
+	config := ResolverConfig{
+		Extensions:   []string{".ts", ".js"},
+		ExtensionMap: map[string][]string{".js": {".ts", ".js"}},
+	}
+
+	exact := newMemResolver(files, config)
+	config.ExtensionMapFirst = true
+	mapped := newMemResolver(files, config)
+
+	for i := 0; i < 20; i++ {
+		if got := exact.Resolve("./foo.js", "/src"); got != "/src/foo.js" {
+			t.Fatalf("Resolve() = %q, want /src/foo.js", got)
+		}
+		if got := mapped.Resolve("./foo.js", "/src"); got != "/src/foo.ts" {
+			t.Fatalf("Resolve() with ExtensionMapFirst = %q, want /src/foo.ts", got)
+		}
+		if got := exact.Resolve("./bar", "/src"); got != "/src/bar.ts" {
+			t.Fatalf("Resolve() = %q, want /src/bar.ts", got)
+		}
+	}
+}
+
+func TestResolveDirectoryIndex(t *testing.T) {
+	files := map[string]string{
+		"src/a/index.js":                   "",
+		"src/b/mod.js":                     "",
+		"src/c/main.js":                    "",
+		"src/c/index.js":                   "",
+		"node_modules/nomain/package.json": `{"name": "nomain"}`,
+		"node_modules/nomain/index.js":     "",
+	}
+
+	tests := []struct {
+		name   string
+		config ResolverConfig
+		input  string
+		want   string
+	}{
+		{"default index", ResolverConfig{}, "./a", "/src/a/index.js"},
+		{"default no mod", ResolverConfig{}, "./b", ""},
+		{"index names", ResolverConfig{IndexNames: []string{"index", "mod", "main"}}, "./b", "/src/b/mod.js"},
+		{"index names order", ResolverConfig{IndexNames: []string{"main", "index"}}, "./c", "/src/c/main.js"},
+		{"legacy index name", ResolverConfig{IndexName: "mod"}, "./b", "/src/b/mod.js"},
+		{"package without main", ResolverConfig{}, "nomain", "/node_modules/nomain/index.js"},
+		{"disabled", ResolverConfig{DisableDirectoryIndex: true}, "./a", ""},
+		{"disabled package", ResolverConfig{DisableDirectoryIndex: true}, "nomain", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Extensions = []string{".js"}
+			r := newMemResolver(files, config)
+			if got := r.Resolve(tt.input, "/src"); got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveMainFields(t *testing.T) {
+	files := map[string]string{
+		"node_modules/dir-main/package.json":    `{"main": "lib"}`,
+		"node_modules/dir-main/lib/index.js":    "",
+		"node_modules/bare-main/package.json":   `{"main": "./index"}`,
+		"node_modules/bare-main/index.js":       "",
+		"node_modules/dual/package.json":        `{"main": "./main.cjs", "module": "./main.mjs", "browser": {"./main.cjs": "./browser.js", "./other.js": false}}`,
+		"node_modules/dual/main.cjs":            "",
+		"node_modules/dual/main.mjs":            "",
+		"node_modules/dual/browser.js":          "",
+		"node_modules/browser-str/package.json": `{"main": "./main.js", "browser": "./browser"}`,
+		"node_modules/browser-str/main.js":      "",
+		"node_modules/browser-str/browser.js":   "",
+	}
+	mainFieldConditions := map[string][]string{
+		"module":  {"import"},
+		"browser": {"browser"},
+	}
+
+	tests := []struct {
+		name       string
+		input      string
+		mainFields []string
+		conditions []string
+		want       string
+	}{
+		{"directory main", "dir-main", nil, nil, "/node_modules/dir-main/lib/index.js"},
+		{"extensionless main", "bare-main", nil, nil, "/node_modules/bare-main/index.js"},
+		{"require", "dual", []string{"browser", "module", "main"}, []string{"require"}, "/node_modules/dual/main.cjs"},
+		{"import", "dual", []string{"browser", "module", "main"}, []string{"import"}, "/node_modules/dual/main.mjs"},
+		{"browser object", "dual", []string{"browser", "module", "main"}, []string{"browser", "import"}, "/node_modules/dual/browser.js"},
+		{"browser string", "browser-str", []string{"browser", "main"}, []string{"browser"}, "/node_modules/browser-str/browser.js"},
+		{"browser inactive", "browser-str", []string{"browser", "main"}, []string{"node"}, "/node_modules/browser-str/main.js"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newMemResolver(files, ResolverConfig{
+				Extensions:          []string{".js"},
+				MainFields:          tt.mainFields,
+				MainFieldConditions: mainFieldConditions,
+				Conditions:          tt.conditions,
+			})
+			if got := r.Resolve(tt.input, "/"); got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveExportsSugar(t *testing.T) {
+	files := map[string]string{
+		"node_modules/arr/package.json":   `{"exports": ["./a.js", "./b.js"]}`,
+		"node_modules/arr/a.js":           "",
+		"node_modules/arr/b.js":           "",
+		"node_modules/cond/package.json":  `{"exports": {"import": "./x.mjs", "require": "./x.cjs"}}`,
+		"node_modules/cond/x.mjs":         "",
+		"node_modules/cond/x.cjs":         "",
+		"node_modules/mixed/package.json": `{"exports": {".": "./x.js", "import": "./x.mjs"}}`,
+		"node_modules/mixed/x.js":         "",
+		"node_modules/null/package.json":  `{"exports": null, "main": "./main.js"}`,
+		"node_modules/null/main.js":       "",
+	}
+	r := newMemResolver(files, ResolverConfig{Conditions: []string{"import", "default"}})
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"arr", "/node_modules/arr/a.js"},
+		{"cond", "/node_modules/cond/x.mjs"},
+		{"mixed", ""},
+		{"null", "/node_modules/null/main.js"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := r.Resolve(tt.input, "/"); got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveArrayFallbacks(t *testing.T) {
+	files := map[string]string{
+		"node_modules/pkg/package.json": `{"exports": {".": ["./missing.js", "./main.js"], "./sub": ["sub.js", "./sub.js"]}}`,
+		"node_modules/pkg/main.js":      "",
+		"node_modules/pkg/sub.js":       "",
+	}
+
+	tests := []struct {
+		input string
+		probe bool
+		want  string
+	}{
+		{"pkg", false, ""},
+		{"pkg", true, "/node_modules/pkg/main.js"},
+		{"pkg/sub", false, "/node_modules/pkg/sub.js"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			r := newMemResolver(files, ResolverConfig{ProbeFallbacks: tt.probe})
+			if got := r.Resolve(tt.input, "/"); got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// cancelingFS cancels its context after a number of operations and honors the
+// context from then on.
+type cancelingFS struct {
+	memFS
+	cancel func()
+	after  int
+	ops    int
+}
+
+func (c *cancelingFS) StatContext(ctx context.Context, name string) (fs.FileInfo, error) {
+	if err := c.op(ctx); err != nil {
+		return nil, err
+	}
+	return c.memFS.Stat(name)
+}
+
+func (c *cancelingFS) ReadFileContext(ctx context.Context, name string) ([]byte, error) {
+	if err := c.op(ctx); err != nil {
+		return nil, err
+	}
+	return c.memFS.ReadFile(name)
+}
+
+func (c *cancelingFS) op(ctx context.Context) error {
+	c.ops++
+	if c.ops > c.after {
+		c.cancel()
+	}
+	return ctx.Err()
+}
+
+func TestResolveContext(t *testing.T) {
+	files := map[string]string{
+		"app/package.json":                  `{"imports": {"#pkg": "pkg"}}`,
+		"app/node_modules/pkg/package.json": `{"exports": {".": "./index.js"}}`,
+		"app/node_modules/pkg/index.js":     "",
+	}
+	spec, _ := NewSpecifier("pkg")
+
+	tests := []struct {
+		name    string
+		resolve func(r *ModuleResolver, ctx context.Context) (string, error)
+		want    string
+	}{
+		{"Resolve", func(r *ModuleResolver, ctx context.Context) (string, error) {
+			return r.ResolveContext(ctx, "pkg", "/app")
+		}, "/app/node_modules/pkg/index.js"},
+		{"ResolveImports", func(r *ModuleResolver, ctx context.Context) (string, error) {
+			return r.ResolveImportsContext(ctx, "#pkg", "/app")
+		}, "/app/node_modules/pkg/index.js"},
+		{"ResolveModuleSpecifier", func(r *ModuleResolver, ctx context.Context) (string, error) {
+			return r.ResolveModuleSpecifierContext(ctx, spec, "/app")
+		}, "/app/node_modules/pkg/index.js"},
+		{"FindUp", func(r *ModuleResolver, ctx context.Context) (string, error) {
+			return r.FindUpContext(ctx, "/app/node_modules/pkg/dist/esm", "package.json")
+		}, "/app/node_modules/pkg/package.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newMemResolver(files, ResolverConfig{Extensions: []string{".js"}})
+			got, err := tt.resolve(r, context.Background())
+			if got != tt.want || err != nil {
+				t.Fatalf("got %q, %v, want %q", got, err, tt.want)
+			}
+
+			for after := range 3 {
+				r.ClearCache()
+				ctx, cancel := context.WithCancel(context.Background())
+				r.Config.FS = &cancelingFS{memFS: r.Config.FS.(memFS), cancel: cancel, after: after}
+				if got, err := tt.resolve(r, ctx); got != "" || !errors.Is(err, context.Canceled) {
+					t.Errorf("canceled after %d operations: got %q, %v, want context.Canceled", after, got, err)
+				}
+				cancel()
+
+				// Canceled lookups must not be cached.
+				if got, err := tt.resolve(r, context.Background()); got != tt.want || err != nil {
+					t.Errorf("after cancellation: got %q, %v, want %q", got, err, tt.want)
+				}
+				r.Config.FS = r.Config.FS.(*cancelingFS).memFS
+			}
+		})
+	}
+}
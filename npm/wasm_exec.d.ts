@@ -1,4 +1,6 @@
-export declare const goGlobal: typeof globalThis;
+export declare const goGlobal: typeof globalThis & {
+	"@startracex/node-resolve": (options: object) => any;
+};
 export declare class Go {
 	run(): Promise<void>;
 }
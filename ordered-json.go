@@ -0,0 +1,168 @@
+package resolve
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+)
+
+type JSONKind int
+
+const (
+	JSONOther JSONKind = iota
+	JSONNull
+	JSONString
+	JSONArray
+	JSONObject
+)
+
+// JSONValue is a decoded JSON value that keeps the order of object keys, which
+// matters for condition precedence. Other holds booleans and numbers as decoded by
+// encoding/json.
+type JSONValue struct {
+	Kind   JSONKind
+	Str    string
+	Items  []*JSONValue
+	Keys   []string
+	Values []*JSONValue
+	Other  any
+}
+
+var errUnexpectedToken = errors.New("resolve: unexpected JSON token")
+
+// ParseJSON decodes the first JSON value of data.
+func ParseJSON(data []byte) (*JSONValue, error) {
+	return parseJSON(json.NewDecoder(bytes.NewReader(data)))
+}
+
+func parseJSON(dec *json.Decoder) (*JSONValue, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case nil:
+		return &JSONValue{Kind: JSONNull}, nil
+	case string:
+		return &JSONValue{Kind: JSONString, Str: t}, nil
+	case json.Delim:
+		switch t {
+		case '[':
+			v := &JSONValue{Kind: JSONArray}
+			for dec.More() {
+				item, err := parseJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				v.Items = append(v.Items, item)
+			}
+			_, err := dec.Token()
+			return v, err
+		case '{':
+			v := &JSONValue{Kind: JSONObject}
+			for dec.More() {
+				keyToken, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, ok := keyToken.(string)
+				if !ok {
+					return nil, errUnexpectedToken
+				}
+				item, err := parseJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				v.Keys = append(v.Keys, key)
+				v.Values = append(v.Values, item)
+			}
+			_, err := dec.Token()
+			return v, err
+		}
+		return nil, errUnexpectedToken
+	}
+
+	return &JSONValue{Kind: JSONOther, Other: token}, nil
+}
+
+// Get returns the value of key in an object, or nil. Like encoding/json, the last
+// of duplicate keys wins.
+func (v *JSONValue) Get(key string) *JSONValue {
+	for i := len(v.Keys) - 1; i >= 0; i-- {
+		if v.Keys[i] == key {
+			return v.Values[i]
+		}
+	}
+	return nil
+}
+
+// Raw converts v back to the representation produced by encoding/json.
+func (v *JSONValue) Raw() any {
+	switch v.Kind {
+	case JSONNull:
+		return nil
+	case JSONString:
+		return v.Str
+	case JSONArray:
+		items := make([]any, len(v.Items))
+		for i, item := range v.Items {
+			items[i] = item.Raw()
+		}
+		return items
+	case JSONObject:
+		m := make(map[string]any, len(v.Keys))
+		for i, key := range v.Keys {
+			m[key] = v.Values[i].Raw()
+		}
+		return m
+	}
+	return v.Other
+}
+
+// jsonTree converts v to the representation produced by encoding/json, except that
+// objects stay *JSONValue to keep their key order.
+func jsonTree(v *JSONValue) any {
+	switch v.Kind {
+	case JSONNull:
+		return nil
+	case JSONString:
+		return v.Str
+	case JSONArray:
+		items := make([]any, len(v.Items))
+		for i, item := range v.Items {
+			items[i] = jsonTree(item)
+		}
+		return items
+	case JSONObject:
+		return v
+	}
+	return v.Other
+}
+
+// conditionEntries returns the keys and values of a conditions object in the order
+// they are tried: manifest order for a *JSONValue and, as maps have no order, by
+// name with "default" last for a map.
+func conditionEntries(value any) (keys []string, values []any, ok bool) {
+	switch v := value.(type) {
+	case *JSONValue:
+		if v.Kind != JSONObject {
+			return nil, nil, false
+		}
+		values = make([]any, len(v.Values))
+		for i, item := range v.Values {
+			values[i] = jsonTree(item)
+		}
+		return v.Keys, values, true
+	case map[string]any:
+		keys = slices.SortedFunc(maps.Keys(v), compareConditions)
+		values = make([]any, len(keys))
+		for i, key := range keys {
+			values[i] = v[key]
+		}
+		return keys, values, true
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,37 @@
+package resolve
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJSON(t *testing.T) {
+	v, err := ParseJSON([]byte(`{"b": ["./b.js", null], "a": {"default": "./a.js", "import": true}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"b", "a"}; !reflect.DeepEqual(v.Keys, want) {
+		t.Errorf("Keys = %v, want %v", v.Keys, want)
+	}
+	if got, want := v.Get("a").Keys, []string{"default", "import"}; !reflect.DeepEqual(got, want) {
+		t.Errorf(`Get("a").Keys = %v, want %v`, got, want)
+	}
+	if got := v.Get("missing"); got != nil {
+		t.Errorf(`Get("missing") = %+v, want nil`, got)
+	}
+
+	want := map[string]any{
+		"b": []any{"./b.js", nil},
+		"a": map[string]any{"default": "./a.js", "import": true},
+	}
+	if got := v.Raw(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Raw() = %v, want %v", got, want)
+	}
+
+	for _, data := range []string{`{"a": }`, `[1, 2`, `}`} {
+		if _, err := ParseJSON([]byte(data)); err == nil {
+			t.Errorf("ParseJSON(%s) succeeded, want error", data)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package resolve
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	CodeModuleNotFound          = "ERR_MODULE_NOT_FOUND"
+	CodePackageImportNotDefined = "ERR_PACKAGE_IMPORT_NOT_DEFINED"
+	CodePackagePathNotExported  = "ERR_PACKAGE_PATH_NOT_EXPORTED"
+	CodeInvalidPackageConfig    = "ERR_INVALID_PACKAGE_CONFIG"
+	CodeUnsupportedDirImport    = "ERR_UNSUPPORTED_DIR_IMPORT"
+)
+
+// ResolveError explains why a specifier failed to resolve, with a Node error code.
+// Path is the manifest or directory involved, if any.
+type ResolveError struct {
+	Code      string
+	Specifier string
+	Base      string
+	Path      string
+	Err       error
+}
+
+func (e *ResolveError) Error() string {
+	var b strings.Builder
+	b.WriteString("resolve: ")
+	switch e.Code {
+	case CodePackageImportNotDefined:
+		b.WriteString("package import specifier " + strconv.Quote(e.Specifier) + " is not defined")
+		if e.Path != "" {
+			b.WriteString(" in " + e.Path)
+		}
+	case CodePackagePathNotExported:
+		b.WriteString("package path " + strconv.Quote(e.Specifier) + " is not exported by " + e.Path)
+	case CodeInvalidPackageConfig:
+		b.WriteString("invalid package config " + e.Path)
+	case CodeUnsupportedDirImport:
+		b.WriteString("directory import " + strconv.Quote(e.Path) + " is not supported")
+	default:
+		b.WriteString("cannot find module " + strconv.Quote(e.Specifier))
+	}
+	b.WriteString(" imported from " + e.Base)
+	if e.Err != nil {
+		b.WriteString(": " + e.Err.Error())
+	}
+	return b.String()
+}
+
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+// TryResolve is like Resolve but returns a *ResolveError describing the failure.
+func (r *ModuleResolver) TryResolve(path, base string) (string, error) {
+	return r.tryResolve(context.Background(), path, base)
+}
+
+func (r *ModuleResolver) tryResolve(ctx context.Context, path, base string) (string, error) {
+	if resolved := r.resolve(ctx, path, base); resolved != "" {
+		return resolved, nil
+	}
+	e := r.resolveError(ctx, path, base)
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return "", e
+}
+
+func (r *ModuleResolver) resolveError(ctx context.Context, path, base string) *ResolveError {
+	e := &ResolveError{Code: CodeModuleNotFound, Specifier: path, Base: base}
+
+	if strings.HasPrefix(path, "#") {
+		manifest, dir, err := r.packageScope(ctx, base)
+		var manifestErr *ManifestError
+		if errors.As(err, &manifestErr) {
+			e.Code, e.Path, e.Err = CodeInvalidPackageConfig, manifestErr.Path, manifestErr.Err
+			return e
+		}
+		e.Code = CodePackageImportNotDefined
+		if err != nil {
+			return e
+		}
+		e.Path = r.Config.Path.Join(dir, r.Config.ManifestFileName)
+		if imports, ok := manifest.Imports(); ok {
+			subpathResolver := NewSubpathResolver(SubpathResolverConfig{
+				Imports:        imports,
+				Conditions:     r.Config.Conditions,
+				ProbeFallbacks: r.Config.ProbeFallbacks,
+			})
+			if len(subpathResolver.ResolveImports(path)) > 0 {
+				e.Code = CodeModuleNotFound
+			}
+		}
+		return e
+	}
+
+	spec, err := NewSpecifier(path)
+	if err != nil || spec.Name == "" {
+		dir := r.Config.Path.Join(base, path)
+		if stat, err := r.stat(ctx, dir); err == nil && stat.IsDir() && r.Config.DisableDirectoryIndex {
+			e.Code, e.Path = CodeUnsupportedDirImport, dir
+		}
+		return e
+	}
+
+	dirs := r.ModulesPaths(base, spec.Name)
+	for _, p := range r.Config.AdditionalModulePaths {
+		dirs = append(dirs, r.Config.Path.Join(p, spec.Name))
+	}
+	for _, dir := range dirs {
+		if stat, err := r.stat(ctx, dir); err != nil || !stat.IsDir() {
+			continue
+		}
+		manifestPath := r.Config.Path.Join(dir, r.Config.ManifestFileName)
+		if _, err := r.stat(ctx, manifestPath); err != nil {
+			return e
+		}
+		manifest, err := r.readManifest(ctx, manifestPath)
+		if err != nil {
+			e.Code, e.Path, e.Err = CodeInvalidPackageConfig, manifestPath, errors.Unwrap(err)
+			return e
+		}
+		exports, ok := manifest.Exports()
+		if !ok {
+			return e
+		}
+		mapping, err := NormalizeExports(exports)
+		if err != nil {
+			e.Code, e.Path, e.Err = CodeInvalidPackageConfig, manifestPath, err
+			return e
+		}
+		subpathResolver := &SubpathResolver{
+			Conditions:     r.Config.Conditions,
+			Exports:        mapping,
+			ProbeFallbacks: r.Config.ProbeFallbacks,
+		}
+		if subpathResolver.Conditions == nil {
+			subpathResolver.Conditions = []string{"default"}
+		}
+		if len(subpathResolver.ResolveExports(spec.Path)) == 0 {
+			e.Code, e.Path = CodePackagePathNotExported, manifestPath
+		}
+		return e
+	}
+
+	return e
+}
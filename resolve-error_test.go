@@ -0,0 +1,58 @@
+package resolve
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTryResolve(t *testing.T) {
+	files := map[string]string{
+		"app/package.json":                    `{"imports": {"#missing": "./missing.js", "#ok": "./ok.js"}}`,
+		"app/ok.js":                           "",
+		"app/dir/index.js":                    "",
+		"app/node_modules/pkg/package.json":   `{"exports": {".": "./index.js"}}`,
+		"app/node_modules/pkg/index.js":       "",
+		"app/node_modules/bad/package.json":   `{"exports": `,
+		"app/node_modules/mixed/package.json": `{"exports": {".": "./index.js", "import": "./index.mjs"}}`,
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		strict   bool
+		want     string
+		wantCode string
+	}{
+		{"found", "#ok", false, "/app/ok.js", ""},
+		{"import not defined", "#nope", false, "", CodePackageImportNotDefined},
+		{"import target missing", "#missing", false, "", CodeModuleNotFound},
+		{"package not found", "nope", false, "", CodeModuleNotFound},
+		{"path not exported", "pkg/internal", false, "", CodePackagePathNotExported},
+		{"invalid manifest", "bad", false, "", CodeInvalidPackageConfig},
+		{"invalid exports", "mixed", false, "", CodeInvalidPackageConfig},
+		{"file not found", "./nope.js", false, "", CodeModuleNotFound},
+		{"directory import", "./dir", true, "", CodeUnsupportedDirImport},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newMemResolver(files, ResolverConfig{
+				Extensions:            []string{".js"},
+				DisableDirectoryIndex: tt.strict,
+			})
+			got, err := r.TryResolve(tt.input, "/app")
+			if got != tt.want {
+				t.Errorf("TryResolve(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var resolveErr *ResolveError
+			if !errors.As(err, &resolveErr) || resolveErr.Code != tt.wantCode {
+				t.Errorf("TryResolve(%q) error = %v, want code %s", tt.input, err, tt.wantCode)
+			}
+		})
+	}
+}
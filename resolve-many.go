@@ -0,0 +1,123 @@
+package resolve
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// Request is a specifier to resolve from Base.
+type Request struct {
+	Specifier string
+	Base      string
+}
+
+// Result is the outcome of a Request. Err is a *ResolveError, or the context error
+// if the request was not completed.
+type Result struct {
+	Resolved string
+	Err      error
+}
+
+type inflightCall struct {
+	done     chan struct{}
+	resolved string
+	err      error
+}
+
+// ResolveMany resolves requests concurrently with at most Config.Concurrency
+// workers and returns their results in order. Identical requests, within the batch
+// or in flight in other calls, are resolved once. Requests not completed when ctx
+// is done fail with its error.
+func (r *ModuleResolver) ResolveMany(ctx context.Context, requests []Request) []Result {
+	results := make([]Result, len(requests))
+
+	indices := make(map[Request][]int)
+	var unique []Request
+	for i, req := range requests {
+		if _, ok := indices[req]; !ok {
+			unique = append(unique, req)
+		}
+		indices[req] = append(indices[req], i)
+	}
+
+	workers := r.Config.Concurrency
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(unique))
+
+	jobs := make(chan Request)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for req := range jobs {
+				if err := ctx.Err(); err != nil {
+					for _, i := range indices[req] {
+						results[i] = Result{Err: err}
+					}
+					continue
+				}
+				resolved, err := r.resolveShared(ctx, req)
+				for _, i := range indices[req] {
+					results[i] = Result{Resolved: resolved, Err: err}
+				}
+			}
+		}()
+	}
+
+	sent := 0
+send:
+	for _, req := range unique {
+		select {
+		case jobs <- req:
+			sent++
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, req := range unique[sent:] {
+		for _, i := range indices[req] {
+			results[i] = Result{Err: ctx.Err()}
+		}
+	}
+	return results
+}
+
+// testHookWait is called when a request waits for an identical one in flight.
+var testHookWait = func(Request) {}
+
+// resolveShared resolves req, or waits for the result of an identical request
+// already in flight. A request abandoned by the context of its caller is retried.
+func (r *ModuleResolver) resolveShared(ctx context.Context, req Request) (string, error) {
+	for {
+		call := &inflightCall{done: make(chan struct{})}
+		existing, loaded := r.inflight.LoadOrStore(req, call)
+		if !loaded {
+			defer func() {
+				r.inflight.Delete(req)
+				close(call.done)
+			}()
+			call.resolved, call.err = r.tryResolve(ctx, req.Specifier, req.Base)
+			return call.resolved, call.err
+		}
+
+		call = existing.(*inflightCall)
+		testHookWait(req)
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded) {
+			continue
+		}
+		return call.resolved, call.err
+	}
+}
@@ -0,0 +1,219 @@
+package resolve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingFS struct {
+	FS
+	stats atomic.Int64
+}
+
+func (c *countingFS) Stat(path string) (fs.FileInfo, error) {
+	c.stats.Add(1)
+	return c.FS.Stat(path)
+}
+
+func TestNewModuleResolverCopiesConfig(t *testing.T) {
+	newConfig := func() *ResolverConfig {
+		return &ResolverConfig{
+			Extensions:          []string{".js"},
+			ExtensionMap:        map[string][]string{".js": {".ts"}},
+			MainFieldConditions: map[string][]string{"module": {"import"}},
+		}
+	}
+	config := newConfig()
+	r := NewModuleResolver(config)
+	if !reflect.DeepEqual(config, newConfig()) {
+		t.Errorf("NewModuleResolver modified its config: %+v", config)
+	}
+	config.Extensions[0] = ".ts"
+	if r.Config.Extensions[0] != ".js" {
+		t.Errorf("resolver shares Extensions with the passed config")
+	}
+	config.ExtensionMap[".js"][0] = ".tsx"
+	if r.Config.ExtensionMap[".js"][0] != ".ts" {
+		t.Errorf("resolver shares ExtensionMap lists with the passed config")
+	}
+	config.MainFieldConditions["module"][0] = "require"
+	if r.Config.MainFieldConditions["module"][0] != "import" {
+		t.Errorf("resolver shares MainFieldConditions lists with the passed config")
+	}
+}
+
+func TestResolveMany(t *testing.T) {
+	files := map[string]string{
+		"app/a.js":                          "",
+		"app/node_modules/pkg/package.json": `{"exports": {".": "./index.js"}}`,
+		"app/node_modules/pkg/index.js":     "",
+	}
+	r := newMemResolver(files, ResolverConfig{Extensions: []string{".js"}, Concurrency: 4})
+	counter := &countingFS{FS: r.Config.FS}
+	r.Config.FS = counter
+
+	r.Resolve("pkg", "/app")
+	single := counter.stats.Swap(0)
+	r.ClearCache()
+
+	var requests []Request
+	var want []Result
+	for i := range 100 {
+		requests = append(requests, Request{"pkg", "/app"})
+		want = append(want, Result{Resolved: "/app/node_modules/pkg/index.js"})
+		if i%10 == 0 {
+			requests = append(requests, Request{"./a", "/app"})
+			want = append(want, Result{Resolved: "/app/a.js"})
+		}
+	}
+	requests = append(requests, Request{"pkg/internal", "/app"})
+
+	got := r.ResolveMany(context.Background(), requests)
+	if len(got) != len(requests) {
+		t.Fatalf("got %d results, want %d", len(got), len(requests))
+	}
+	if !reflect.DeepEqual(got[:len(want)], want) {
+		t.Errorf("ResolveMany() = %+v, want %+v", got[:len(want)], want)
+	}
+	var resolveErr *ResolveError
+	if last := got[len(got)-1]; !errors.As(last.Err, &resolveErr) || resolveErr.Code != CodePackagePathNotExported {
+		t.Errorf("ResolveMany() last = %+v, want %s", last, CodePackagePathNotExported)
+	}
+
+	r.ClearCache()
+	counter.stats.Store(0)
+	r.ResolveMany(context.Background(), []Request{{"pkg", "/app"}, {"pkg", "/app"}, {"pkg", "/app"}})
+	if stats := counter.stats.Load(); stats != single {
+		t.Errorf("identical requests made %d Stat calls, want %d", stats, single)
+	}
+}
+
+type blockingFS struct {
+	*countingFS
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingFS) Stat(path string) (fs.FileInfo, error) {
+	b.once.Do(func() {
+		close(b.started)
+		<-b.release
+	})
+	return b.countingFS.Stat(path)
+}
+
+func TestResolveManyShared(t *testing.T) {
+	files := map[string]string{
+		"app/node_modules/pkg/package.json": `{"exports": {".": "./index.js"}}`,
+		"app/node_modules/pkg/index.js":     "",
+	}
+	r := newMemResolver(files, ResolverConfig{})
+	counter := &countingFS{FS: r.Config.FS}
+	r.Config.FS = counter
+
+	r.Resolve("pkg", "/app")
+	single := counter.stats.Swap(0)
+	r.ClearCache()
+
+	blocking := &blockingFS{countingFS: counter, started: make(chan struct{}), release: make(chan struct{})}
+	r.Config.FS = blocking
+
+	waiting := make(chan struct{})
+	testHookWait = func(Request) { close(waiting) }
+	t.Cleanup(func() { testHookWait = func(Request) {} })
+
+	requests := []Request{{"pkg", "/app"}}
+	results := make([][]Result, 2)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.ResolveMany(context.Background(), requests)
+		}()
+		if i == 0 {
+			<-blocking.started
+		}
+	}
+	// Complete the first call only once the second waits for it.
+	select {
+	case <-waiting:
+	case <-time.After(10 * time.Second):
+		t.Error("second call did not wait for the first")
+	}
+	close(blocking.release)
+	wg.Wait()
+
+	want := []Result{{Resolved: "/app/node_modules/pkg/index.js"}}
+	for i, got := range results {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ResolveMany() call %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if stats := counter.stats.Load(); stats != single {
+		t.Errorf("concurrent calls made %d Stat calls, want %d", stats, single)
+	}
+}
+
+func TestResolveManyCanceled(t *testing.T) {
+	r := newMemResolver(map[string]string{"app/a.js": ""}, ResolverConfig{Extensions: []string{".js"}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, result := range r.ResolveMany(ctx, []Request{{"./a", "/app"}, {"./b", "/app"}}) {
+		if !errors.Is(result.Err, context.Canceled) || result.Resolved != "" {
+			t.Errorf("result = %+v, want context.Canceled", result)
+		}
+	}
+}
+
+func TestResolveConcurrent(t *testing.T) {
+	files := map[string]string{}
+	want := map[string]string{}
+	for i := range 20 {
+		name := fmt.Sprintf("pkg%d", i)
+		files["app/node_modules/"+name+"/package.json"] = `{"exports": {".": {"import": "./index.mjs", "default": "./index.js"}}, "imports": {"#self": "./index.js"}}`
+		files["app/node_modules/"+name+"/index.mjs"] = ""
+		files["app/node_modules/"+name+"/index.js"] = ""
+		want[name] = filepath.Join("app", "node_modules", name, "index.mjs")
+	}
+	root := writeFiles(t, files)
+	r := NewModuleResolver(&ResolverConfig{
+		Extensions: []string{".js"},
+		Conditions: []string{"import", "default"},
+	})
+	base := filepath.Join(root, "app")
+
+	var wg sync.WaitGroup
+	for g := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				name := fmt.Sprintf("pkg%d", (g+i)%20)
+				if got := r.Resolve(name, base); got != filepath.Join(root, want[name]) {
+					t.Errorf("Resolve(%q) = %q", name, got)
+					return
+				}
+				pkgDir := filepath.Join(base, "node_modules", name)
+				if got := r.Resolve("#self", pkgDir); got != filepath.Join(pkgDir, "index.js") {
+					t.Errorf("Resolve(#self) from %s = %q", name, got)
+					return
+				}
+				if i%50 == 0 {
+					r.ClearCache()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,134 @@
+package resolve
+
+import (
+	"errors"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var ErrNoSpecifier = errors.New("resolve: no specifier resolves to target")
+
+// SpecifierFor returns the specifier to write in fromDir to import target. Within the
+// same package, an imports alias is preferred over a relative path; another package
+// is only reached through a package specifier honoring its exports. Every candidate
+// must resolve back to target.
+func (r *ModuleResolver) SpecifierFor(target, fromDir string) (string, error) {
+	for _, candidate := range r.specifierCandidates(target, fromDir) {
+		if r.Resolve(candidate, fromDir) == target {
+			return candidate, nil
+		}
+	}
+	return "", ErrNoSpecifier
+}
+
+func (r *ModuleResolver) specifierCandidates(target, fromDir string) []string {
+	var candidates []string
+
+	relative := r.relative(fromDir, target)
+	if !strings.HasPrefix(relative, "../") {
+		relative = subpathPrefix + relative
+	}
+
+	targetManifest, targetDir, err := r.FindPackageScope(r.Config.Path.Dir(target))
+	if err != nil {
+		return []string{relative}
+	}
+	subpath := subpathPrefix + r.relative(targetDir, target)
+
+	if _, scopeDir, err := r.FindPackageScope(fromDir); err == nil && scopeDir == targetDir {
+		if imports, ok := targetManifest.Imports(); ok {
+			candidates = append(candidates, invertMapping(NormalizeMapping(imports), r.Config.Conditions, subpath)...)
+		}
+		return append(candidates, relative)
+	}
+
+	if name := targetManifest.Name(); name != "" {
+		if exports, ok := targetManifest.Exports(); ok {
+			mapping, err := NormalizeExports(exports)
+			if err == nil {
+				for _, key := range invertMapping(mapping, r.Config.Conditions, subpath) {
+					candidates = append(candidates, path.Join(name, key))
+				}
+			}
+		} else {
+			candidates = append(candidates, name, path.Join(name, subpath))
+		}
+	}
+
+	if slices.Contains(strings.Split(relative, "/"), r.Config.ModulesDirectoryName) {
+		return candidates
+	}
+	return append(candidates, relative)
+}
+
+// invertMapping returns the keys of mapping whose targets under conditions match
+// target, exact keys first and then pattern keys from the most specific, with the
+// wildcard recovered from target.
+func invertMapping(mapping map[string]any, conditions []string, target string) []string {
+	var exact, patterns []string
+
+	keys := slices.SortedFunc(maps.Keys(mapping), func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+	for _, key := range keys {
+		wildcardKey := strings.ContainsRune(key, '*')
+		for _, t := range resolveMappingValue(mapping[key], conditions) {
+			if !wildcardKey {
+				if path.Clean(t) == path.Clean(target) {
+					exact = append(exact, key)
+					break
+				}
+				continue
+			}
+
+			before, after, ok := strings.Cut(t, "*")
+			if !ok || len(target) < len(before)+len(after) {
+				continue
+			}
+			if strings.HasPrefix(target, before) && strings.HasSuffix(target, after) {
+				wildcard := target[len(before) : len(target)-len(after)]
+				if key := strings.Replace(key, "*", wildcard, 1); !slices.Contains(patterns, key) {
+					patterns = append(patterns, key)
+				}
+				break
+			}
+		}
+	}
+
+	return append(exact, patterns...)
+}
+
+// relative returns the slash-separated path of to relative to from.
+func (r *ModuleResolver) relative(from, to string) string {
+	if _, ok := r.Config.Path.(*osPath); ok {
+		if rel, err := filepath.Rel(from, to); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+
+	fromParts := splitPath(from)
+	toParts := splitPath(to)
+	common := 0
+	for common < len(fromParts) && common < len(toParts) && fromParts[common] == toParts[common] {
+		common++
+	}
+	parts := make([]string, 0, len(fromParts)-common+len(toParts)-common)
+	for range fromParts[common:] {
+		parts = append(parts, "..")
+	}
+	return strings.Join(append(parts, toParts[common:]...), "/")
+}
+
+func splitPath(p string) []string {
+	p = path.Clean(strings.ReplaceAll(p, "\\", "/"))
+	if p == "/" || p == "." {
+		return nil
+	}
+	return strings.Split(strings.TrimPrefix(p, "/"), "/")
+}
@@ -0,0 +1,82 @@
+package resolve
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestInvertMapping(t *testing.T) {
+	mapping := map[string]any{
+		".":              map[string]any{"import": "./index.mjs", "default": "./index.js"},
+		"./utils/*":      "./src/utils/*.js",
+		"./utils/fs/*":   "./src/utils/fs/*.js",
+		"./package.json": "./package.json",
+	}
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"./index.mjs", []string{"."}},
+		{"./index.js", nil},
+		{"./src/utils/fs/read.js", []string{"./utils/fs/read"}},
+		{"./src/utils/a/b.js", []string{"./utils/a/b"}},
+		{"./package.json", []string{"./package.json"}},
+		{"./other.js", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := invertMapping(mapping, []string{"import", "default"}, tt.target); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("invertMapping(%q) = %v, want %v", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpecifierFor(t *testing.T) {
+	r := newMemResolver(map[string]string{
+		"app/package.json":      `{"name": "app", "imports": {"#utils/*": "./src/utils/*.js"}}`,
+		"app/src/utils/math.js": "",
+		"app/src/pages/home.js": "",
+		"app/src/other.js":      "",
+		"app/node_modules/lib/package.json": `{
+			"name": "lib",
+			"exports": {
+				".": "./dist/index.js",
+				"./feature/*": "./dist/feature/*.js",
+				"./feature/internal/*": null
+			}
+		}`,
+		"app/node_modules/lib/dist/index.js":              "",
+		"app/node_modules/lib/dist/feature/a.js":          "",
+		"app/node_modules/lib/dist/feature/internal/x.js": "",
+		"app/node_modules/legacy/package.json":            `{"name": "legacy", "main": "./main.js"}`,
+		"app/node_modules/legacy/main.js":                 "",
+		"app/node_modules/legacy/lib/util.js":             "",
+	}, ResolverConfig{})
+	from := "/app/src/pages"
+
+	tests := []struct {
+		target  string
+		want    string
+		wantErr error
+	}{
+		{"/app/src/utils/math.js", "#utils/math", nil},
+		{"/app/src/other.js", "../other.js", nil},
+		{"/app/src/pages/home.js", "./home.js", nil},
+		{"/app/node_modules/lib/dist/index.js", "lib", nil},
+		{"/app/node_modules/lib/dist/feature/a.js", "lib/feature/a", nil},
+		{"/app/node_modules/lib/dist/feature/internal/x.js", "", ErrNoSpecifier},
+		{"/app/node_modules/legacy/main.js", "legacy", nil},
+		{"/app/node_modules/legacy/lib/util.js", "legacy/lib/util.js", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, err := r.SpecifierFor(tt.target, from)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("SpecifierFor(%q) = (%q, %v), want (%q, %v)", tt.target, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
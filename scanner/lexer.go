@@ -0,0 +1,320 @@
+package scanner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenPunct
+	// tokenString is a string literal or a template literal without substitutions.
+	tokenString
+	tokenTemplate
+	tokenOther
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value string
+	start int
+	end   int
+}
+
+// SyntaxError reports source the lexer cannot tokenize, such as an unterminated
+// string or comment.
+type SyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("scanner: %s at offset %d", e.Msg, e.Offset)
+}
+
+// regexKeywords may be followed by a regular expression rather than a division.
+var regexKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "case": true,
+	"do": true, "else": true, "yield": true, "await": true,
+}
+
+type lexer struct {
+	src    string
+	pos    int
+	tokens []token
+	// templates holds the brace depth at which each open template substitution
+	// resumes its template.
+	templates []int
+	braces    int
+}
+
+// tokenize splits src into tokens, skipping comments, whitespace and the bodies of
+// regular expressions and template literals.
+func tokenize(src string) ([]token, error) {
+	l := &lexer{src: src}
+	if strings.HasPrefix(src, "#!") {
+		l.skipLine()
+	}
+	for l.pos < len(src) {
+		if err := l.next(); err != nil {
+			return l.tokens, err
+		}
+	}
+	if len(l.templates) > 0 {
+		return l.tokens, &SyntaxError{Offset: len(src), Msg: "unterminated template literal"}
+	}
+	return l.tokens, nil
+}
+
+func (l *lexer) emit(kind tokenKind, start int, value string) {
+	l.tokens = append(l.tokens, token{kind: kind, text: l.src[start:l.pos], value: value, start: start, end: l.pos})
+}
+
+func (l *lexer) next() error {
+	c := l.src[l.pos]
+	start := l.pos
+	switch {
+	case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+		l.pos++
+	case c == '/' && l.peek(1) == '/':
+		l.skipLine()
+	case c == '/' && l.peek(1) == '*':
+		end := strings.Index(l.src[l.pos+2:], "*/")
+		if end == -1 {
+			return &SyntaxError{Offset: start, Msg: "unterminated comment"}
+		}
+		l.pos += end + 4
+	case c == '\'' || c == '"':
+		value, err := l.string(c)
+		if err != nil {
+			return err
+		}
+		l.emit(tokenString, start, value)
+	case c == '`':
+		l.pos++
+		return l.template(start)
+	case c == '}' && len(l.templates) > 0 && l.templates[len(l.templates)-1] == l.braces:
+		l.templates = l.templates[:len(l.templates)-1]
+		l.pos++
+		return l.template(start)
+	case c == '/' && l.regexAllowed():
+		return l.regex()
+	case isIdentStart(c):
+		for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+			l.pos++
+		}
+		l.emit(tokenIdent, start, "")
+	case c >= '0' && c <= '9':
+		for l.pos < len(l.src) && (isIdentPart(l.src[l.pos]) || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		l.emit(tokenOther, start, "")
+	default:
+		l.pos++
+		switch c {
+		case '{':
+			l.braces++
+		case '}':
+			l.braces--
+		case '?':
+			if l.peek(0) == '.' && !isDigit(l.peek(1)) {
+				l.pos++
+			}
+		case '.':
+			if l.peek(0) == '.' && l.peek(1) == '.' {
+				l.pos += 2
+			}
+		}
+		l.emit(tokenPunct, start, "")
+	}
+	return nil
+}
+
+func (l *lexer) peek(offset int) byte {
+	if l.pos+offset < len(l.src) {
+		return l.src[l.pos+offset]
+	}
+	return 0
+}
+
+func (l *lexer) skipLine() {
+	for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+		l.pos++
+	}
+}
+
+// regexAllowed reports whether a slash at the current position starts a regular
+// expression, judged by the previous token.
+func (l *lexer) regexAllowed() bool {
+	if len(l.tokens) == 0 {
+		return true
+	}
+	prev := l.tokens[len(l.tokens)-1]
+	switch prev.kind {
+	case tokenIdent:
+		return regexKeywords[prev.text]
+	case tokenPunct:
+		return prev.text != ")" && prev.text != "]"
+	}
+	return false
+}
+
+func (l *lexer) regex() error {
+	start := l.pos
+	l.pos++
+	inClass := false
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\':
+			l.pos++
+		case c == '\n':
+			return &SyntaxError{Offset: start, Msg: "unterminated regular expression"}
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			l.pos++
+			for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+				l.pos++
+			}
+			l.emit(tokenOther, start, "")
+			return nil
+		}
+		l.pos++
+	}
+	return &SyntaxError{Offset: start, Msg: "unterminated regular expression"}
+}
+
+// string reads a quoted string literal and returns its decoded value.
+func (l *lexer) string(quote byte) (string, error) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == quote:
+			l.pos++
+			return b.String(), nil
+		case c == '\n':
+			return "", &SyntaxError{Offset: start, Msg: "unterminated string"}
+		case c == '\\':
+			l.escape(&b)
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return "", &SyntaxError{Offset: start, Msg: "unterminated string"}
+}
+
+// template reads template characters after a backquote or the closing brace of a
+// substitution, up to the closing backquote or the next substitution.
+func (l *lexer) template(start int) error {
+	var b strings.Builder
+	head := l.src[start] == '`'
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '`':
+			l.pos++
+			if head {
+				l.emit(tokenString, start, b.String())
+			} else {
+				l.emit(tokenTemplate, start, "")
+			}
+			return nil
+		case c == '$' && l.peek(1) == '{':
+			l.pos += 2
+			l.templates = append(l.templates, l.braces)
+			l.emit(tokenTemplate, start, "")
+			return nil
+		case c == '\\':
+			l.escape(&b)
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return &SyntaxError{Offset: start, Msg: "unterminated template literal"}
+}
+
+// escape decodes the escape sequence at the current position into b.
+func (l *lexer) escape(b *strings.Builder) {
+	l.pos++
+	if l.pos >= len(l.src) {
+		return
+	}
+	c := l.src[l.pos]
+	l.pos++
+	switch c {
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'v':
+		b.WriteByte('\v')
+	case '0':
+		b.WriteByte(0)
+	case '\r':
+		if l.peek(0) == '\n' {
+			l.pos++
+		}
+	case '\n':
+	case 'x':
+		l.codePoint(b, 2)
+	case 'u':
+		if l.peek(0) == '{' {
+			end := strings.IndexByte(l.src[l.pos:], '}')
+			if end != -1 {
+				if r, err := strconv.ParseUint(l.src[l.pos+1:l.pos+end], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					l.pos += end + 1
+					return
+				}
+			}
+			b.WriteByte('u')
+			return
+		}
+		l.codePoint(b, 4)
+	default:
+		b.WriteByte(c)
+	}
+}
+
+func (l *lexer) codePoint(b *strings.Builder, digits int) {
+	if l.pos+digits <= len(l.src) {
+		if r, err := strconv.ParseUint(l.src[l.pos:l.pos+digits], 16, 32); err == nil {
+			b.WriteRune(rune(r))
+			l.pos += digits
+			return
+		}
+	}
+	b.WriteByte(l.src[l.pos-1])
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$' || c == '\\' || c >= utf8.RuneSelf
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
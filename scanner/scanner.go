@@ -0,0 +1,323 @@
+// Package scanner extracts the module specifiers of JavaScript and TypeScript
+// source, in the spirit of es-module-lexer and cjs-module-lexer: a lexer skips
+// comments, strings, templates and regular expressions, and the import forms are
+// recognized from the token stream without building a syntax tree.
+package scanner
+
+import (
+	"path/filepath"
+
+	resolve "github.com/startracex/node-resolve"
+)
+
+const (
+	// KindStatic is an import declaration, including side-effect imports.
+	KindStatic = "static"
+	// KindDynamic is an import() call with a string argument.
+	KindDynamic = "dynamic"
+	// KindRequire is a require() call with a string argument, or a TypeScript
+	// import x = require() declaration.
+	KindRequire = "require"
+	// KindTypeOnly is an import type or export type declaration, or a typeof
+	// import() type, which are erased from the emitted JavaScript.
+	KindTypeOnly = "type-only"
+	// KindReExport is an export declaration with a from clause.
+	KindReExport = "re-export"
+)
+
+// Import is a module specifier found in source. Start and End delimit the
+// specifier inside its quotes, and StatementStart and StatementEnd the whole
+// declaration or call. Attributes are the import attributes of a with or assert
+// clause.
+type Import struct {
+	Specifier      string            `json:"specifier"`
+	Kind           string            `json:"kind"`
+	Start          int               `json:"start"`
+	End            int               `json:"end"`
+	StatementStart int               `json:"statementStart"`
+	StatementEnd   int               `json:"statementEnd"`
+	Attributes     map[string]string `json:"attributes,omitempty"`
+}
+
+// Scan returns the imports of src in source order. On a *SyntaxError it returns
+// the imports found before the error.
+func Scan(src []byte) ([]Import, error) {
+	tokens, err := tokenize(string(src))
+	s := &scanner{tokens: tokens}
+	for s.pos = 0; s.pos < len(s.tokens); s.pos++ {
+		// A keyword not starting an import is rescanned from the next token.
+		start, found := s.pos, len(s.imports)
+		s.scan()
+		if len(s.imports) == found {
+			s.pos = start
+		}
+	}
+	return s.imports, err
+}
+
+// Requests returns the requests resolving imports from the file at path, to be
+// passed to ModuleResolver.ResolveMany.
+func Requests(path string, imports []Import) []resolve.Request {
+	base := filepath.Dir(path)
+	requests := make([]resolve.Request, len(imports))
+	for i, imp := range imports {
+		requests[i] = resolve.Request{Specifier: imp.Specifier, Base: base}
+	}
+	return requests
+}
+
+type scanner struct {
+	tokens  []token
+	pos     int
+	imports []Import
+}
+
+func (s *scanner) at(offset int) token {
+	if i := s.pos + offset; i >= 0 && i < len(s.tokens) {
+		return s.tokens[i]
+	}
+	return token{kind: tokenOther}
+}
+
+func (s *scanner) is(offset int, kind tokenKind, text string) bool {
+	t := s.at(offset)
+	return t.kind == kind && (text == "" || t.text == text)
+}
+
+// keyword reports whether the current token is the identifier name, not used as a
+// property name.
+func (s *scanner) keyword(name string) bool {
+	if !s.is(0, tokenIdent, name) {
+		return false
+	}
+	prev := s.at(-1)
+	return prev.kind != tokenPunct || prev.text != "." && prev.text != "?."
+}
+
+func (s *scanner) scan() {
+	switch {
+	case s.keyword("import"):
+		s.importKeyword()
+	case s.keyword("export"):
+		s.exportKeyword()
+	case s.keyword("require") && s.is(1, tokenPunct, "("):
+		start := s.at(0).start
+		s.pos++
+		s.call(KindRequire, start)
+	}
+}
+
+func (s *scanner) importKeyword() {
+	start := s.at(0).start
+	switch {
+	case s.is(1, tokenPunct, "("):
+		kind := KindDynamic
+		if s.is(-1, tokenIdent, "typeof") {
+			kind = KindTypeOnly
+		}
+		s.pos++
+		s.call(kind, start)
+		return
+	case s.is(1, tokenPunct, "."), s.is(1, tokenPunct, ":"):
+		// import.meta, or a property named import.
+		return
+	}
+
+	kind := KindStatic
+	if s.is(1, tokenIdent, "type") && s.typeModifier(2) {
+		kind = KindTypeOnly
+	}
+	s.pos++
+	s.clause(kind, start)
+}
+
+func (s *scanner) exportKeyword() {
+	start := s.at(0).start
+	kind := KindReExport
+	if s.is(1, tokenIdent, "type") && (s.is(2, tokenPunct, "{") || s.is(2, tokenPunct, "*")) {
+		kind = KindTypeOnly
+		s.pos++
+	}
+	switch {
+	case s.is(1, tokenPunct, "*"), s.is(1, tokenPunct, "{"):
+		s.pos++
+		s.clause(kind, start)
+	}
+}
+
+// typeModifier reports whether a type identifier before the token at offset is the
+// type modifier of import type, rather than a default import named type.
+func (s *scanner) typeModifier(offset int) bool {
+	switch next := s.at(offset); {
+	case next.kind == tokenPunct:
+		return next.text == "{" || next.text == "*"
+	case next.kind == tokenIdent && next.text == "from":
+		// import type from "x" imports a default named type; import type from from "x"
+		// imports a type named from.
+		return s.is(offset+1, tokenIdent, "from")
+	case next.kind == tokenIdent:
+		return true
+	}
+	return false
+}
+
+// clause scans an import or export clause up to its from keyword and specifier.
+func (s *scanner) clause(kind string, start int) {
+	if s.is(0, tokenString, "") {
+		if kind == KindReExport {
+			return
+		}
+		s.add(kind, start, s.at(0))
+		return
+	}
+
+	depth := 0
+	for ; s.pos < len(s.tokens); s.pos++ {
+		t := s.at(0)
+		switch {
+		case t.kind == tokenPunct && t.text == "{":
+			depth++
+		case t.kind == tokenPunct && t.text == "}":
+			depth--
+		case depth > 0:
+			continue
+		case s.is(-1, tokenPunct, "}") && !(t.kind == tokenIdent && t.text == "from"):
+			// Only a from clause may follow the braces.
+			return
+		case t.kind == tokenIdent && (t.text == "import" || t.text == "export"):
+			return
+		case t.kind == tokenIdent && t.text == "from" && s.is(1, tokenString, ""):
+			s.pos++
+			s.add(kind, start, s.at(0))
+			return
+		case t.kind == tokenPunct && t.text == "=" && kind != KindReExport:
+			// import x = require("x")
+			if s.is(1, tokenIdent, "require") && s.is(2, tokenPunct, "(") {
+				if kind != KindTypeOnly {
+					kind = KindRequire
+				}
+				s.pos += 2
+				s.call(kind, start)
+			}
+			return
+		case t.kind == tokenPunct && t.text != "*" && t.text != ",":
+			return
+		case t.kind == tokenString || t.kind == tokenTemplate || t.kind == tokenOther:
+			return
+		}
+		if depth < 0 {
+			return
+		}
+	}
+}
+
+// call scans the arguments of an import() or require() call at the opening
+// parenthesis, recording a string specifier and import() attributes.
+func (s *scanner) call(kind string, start int) {
+	if !s.is(1, tokenString, "") {
+		return
+	}
+	literal := s.at(1)
+	s.pos += 2
+	var attributes map[string]string
+	if s.is(0, tokenPunct, ",") && kind != KindRequire {
+		s.pos++
+		attributes = s.options()
+		if s.is(0, tokenPunct, ",") {
+			s.pos++
+		}
+	}
+	if !s.is(0, tokenPunct, ")") {
+		return
+	}
+	imp := newImport(kind, start, literal)
+	imp.StatementEnd = s.at(0).end
+	imp.Attributes = attributes
+	s.imports = append(s.imports, imp)
+}
+
+// options reads the options argument of import(), returning the attributes of its
+// with or assert property.
+func (s *scanner) options() map[string]string {
+	if !s.is(0, tokenPunct, "{") {
+		return nil
+	}
+	var attributes map[string]string
+	s.pos++
+	for s.pos < len(s.tokens) && !s.is(0, tokenPunct, "}") {
+		key := s.at(0)
+		if (key.kind == tokenIdent || key.kind == tokenString) && s.is(1, tokenPunct, ":") &&
+			(s.propertyName(key) == "with" || s.propertyName(key) == "assert") {
+			s.pos += 2
+			attributes = s.attributes()
+		} else {
+			s.pos++
+		}
+		if s.is(0, tokenPunct, "}") {
+			break
+		}
+		if s.is(0, tokenPunct, ",") {
+			s.pos++
+		}
+	}
+	if s.is(0, tokenPunct, "}") {
+		s.pos++
+	}
+	return attributes
+}
+
+// attributes reads an attributes object, leaving the position after it.
+func (s *scanner) attributes() map[string]string {
+	if !s.is(0, tokenPunct, "{") {
+		return nil
+	}
+	attributes := make(map[string]string)
+	s.pos++
+	for s.pos < len(s.tokens) {
+		key := s.at(0)
+		switch {
+		case key.kind == tokenPunct && key.text == "}":
+			s.pos++
+			return attributes
+		case key.kind == tokenPunct && key.text == ",":
+			s.pos++
+		case (key.kind == tokenIdent || key.kind == tokenString) && s.is(1, tokenPunct, ":") && s.is(2, tokenString, ""):
+			attributes[s.propertyName(key)] = s.at(2).value
+			s.pos += 3
+		default:
+			return attributes
+		}
+	}
+	return attributes
+}
+
+func (s *scanner) propertyName(t token) string {
+	if t.kind == tokenString {
+		return t.value
+	}
+	return t.text
+}
+
+// add records a declaration whose specifier is the current token, together with
+// a following with or assert clause.
+func (s *scanner) add(kind string, start int, literal token) {
+	imp := newImport(kind, start, literal)
+	if (s.is(1, tokenIdent, "with") || s.is(1, tokenIdent, "assert")) && s.is(2, tokenPunct, "{") {
+		s.pos += 2
+		imp.Attributes = s.attributes()
+		imp.StatementEnd = s.at(-1).end
+		s.pos--
+	}
+	s.imports = append(s.imports, imp)
+}
+
+func newImport(kind string, start int, literal token) Import {
+	return Import{
+		Specifier:      literal.value,
+		Kind:           kind,
+		Start:          literal.start + 1,
+		End:            literal.end - 1,
+		StatementStart: start,
+		StatementEnd:   literal.end,
+	}
+}
@@ -0,0 +1,124 @@
+package scanner
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	resolve "github.com/startracex/node-resolve"
+)
+
+type found struct {
+	specifier  string
+	kind       string
+	attributes map[string]string
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []found
+	}{
+		{"side effect", `import "./polyfill.js";`, []found{{"./polyfill.js", KindStatic, nil}}},
+		{"default", `import React from 'react'`, []found{{"react", KindStatic, nil}}},
+		{"named", `import { a, b as c } from "./a.js"`, []found{{"./a.js", KindStatic, nil}}},
+		{"namespace", `import * as path from "node:path"`, []found{{"node:path", KindStatic, nil}}},
+		{"default and named", `import x, { y } from "x"`, []found{{"x", KindStatic, nil}}},
+		{"inline type", `import { type A, b } from "x"`, []found{{"x", KindStatic, nil}}},
+		{"default named type", `import type from "x"`, []found{{"x", KindStatic, nil}}},
+		{"type named from", `import type from from "x"`, []found{{"x", KindTypeOnly, nil}}},
+		{"type", `import type { A } from "./types"`, []found{{"./types", KindTypeOnly, nil}}},
+		{"type default", `import type A from "a"`, []found{{"a", KindTypeOnly, nil}}},
+		{"type namespace", `import type * as T from "t"`, []found{{"t", KindTypeOnly, nil}}},
+		{"attributes", `import data from "./data.json" with { type: "json" };`, []found{{"./data.json", KindStatic, map[string]string{"type": "json"}}}},
+		{"assert", `import data from "./data.json" assert { "type": 'json' }`, []found{{"./data.json", KindStatic, map[string]string{"type": "json"}}}},
+		{"export all", `export * from "./all"`, []found{{"./all", KindReExport, nil}}},
+		{"export namespace", `export * as ns from "./ns"`, []found{{"./ns", KindReExport, nil}}},
+		{"export named", `export { a, default as b } from "./named"`, []found{{"./named", KindReExport, nil}}},
+		{"export type", `export type { A } from "./a"; export type * from "./b"`, []found{{"./a", KindTypeOnly, nil}, {"./b", KindTypeOnly, nil}}},
+		{"export local", "export { a }\nimport b from \"b\"", []found{{"b", KindStatic, nil}}},
+		{"export declarations", `export const a = 1; export default from; export type A = B;`, nil},
+		{"dynamic", `const m = await import("./lazy.js")`, []found{{"./lazy.js", KindDynamic, nil}}},
+		{"dynamic template", "import(`./lazy.js`)", []found{{"./lazy.js", KindDynamic, nil}}},
+		{"dynamic options", `import("./data.json", { with: { type: "json" } })`, []found{{"./data.json", KindDynamic, map[string]string{"type": "json"}}}},
+		{"dynamic expression", "import(name); import(`./${name}.js`); import(\"./a\" + b)", nil},
+		{"typeof import", `let m: typeof import("./mod")`, []found{{"./mod", KindTypeOnly, nil}}},
+		{"require", `const fs = require("fs"), { join } = require('path');`, []found{{"fs", KindRequire, nil}, {"path", KindRequire, nil}}},
+		{"import equals", `import fs = require("fs"); export import p = require("path"); import type T = require("t")`, []found{{"fs", KindRequire, nil}, {"path", KindRequire, nil}, {"t", KindTypeOnly, nil}}},
+		{"member access", `obj.import("a"); obj.require("b"); obj?.require("c"); x = { import: "d", require: "e" }; import.meta.url`, nil},
+		{"comments", "// import \"a\"\n/* require(\"b\") */ import c from \"c\"", []found{{"c", KindStatic, nil}}},
+		{"strings", `const s = "import a from 'a'"; const t = 'require("b")'`, nil},
+		{"templates", "const s = `import a from \"a\" ${ `${require(\"b\")}` } require(\"c\")`; import d from \"d\"", []found{{"b", KindRequire, nil}, {"d", KindStatic, nil}}},
+		{"regex", `const re = /import "a"|require\("b"\)/g; x = a / require("c") / 2`, []found{{"c", KindRequire, nil}}},
+		{"regex class", `const re = /[/"]/; import a from "a"`, []found{{"a", KindStatic, nil}}},
+		{"escapes", `import a from "./\x61\u{62}c.js"`, []found{{"./abc.js", KindStatic, nil}}},
+		{"shebang", "#!/usr/bin/env node\nrequire(\"a\")", []found{{"a", KindRequire, nil}}},
+		{"no semicolons", "import a from \"a\"\nexport * from \"b\"\nrequire(\"c\")", []found{{"a", KindStatic, nil}, {"b", KindReExport, nil}, {"c", KindRequire, nil}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imports, err := Scan([]byte(tt.src))
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			var got []found
+			for _, imp := range imports {
+				got = append(got, found{imp.Specifier, imp.Kind, imp.Attributes})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan(%q) = %v, want %v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanRanges(t *testing.T) {
+	src := `import a from "./a.js" with { type: "js" }; const b = require('b');`
+	imports, err := Scan([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		specifier string
+		statement string
+	}{
+		{"./a.js", `import a from "./a.js" with { type: "js" }`},
+		{"b", `require('b')`},
+	}
+	if len(imports) != len(want) {
+		t.Fatalf("got %d imports, want %d", len(imports), len(want))
+	}
+	for i, imp := range imports {
+		if got := src[imp.Start:imp.End]; got != want[i].specifier {
+			t.Errorf("imports[%d] specifier range = %q, want %q", i, got, want[i].specifier)
+		}
+		if got := src[imp.StatementStart:imp.StatementEnd]; got != want[i].statement {
+			t.Errorf("imports[%d] statement range = %q, want %q", i, got, want[i].statement)
+		}
+	}
+}
+
+func TestScanSyntaxError(t *testing.T) {
+	imports, err := Scan([]byte("import a from \"a\"\nconst s = 'unterminated\nrequire(\"b\")"))
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Offset != 28 {
+		t.Errorf("Scan() error = %v, want a SyntaxError at offset 28", err)
+	}
+	if len(imports) != 1 || imports[0].Specifier != "a" {
+		t.Errorf("Scan() = %+v, want the import before the error", imports)
+	}
+}
+
+func TestRequests(t *testing.T) {
+	file := filepath.Join("src", "app", "main.js")
+	got := Requests(file, []Import{{Specifier: "react"}, {Specifier: "./util.js"}})
+	want := []resolve.Request{
+		{Specifier: "react", Base: filepath.Join("src", "app")},
+		{Specifier: "./util.js", Base: filepath.Join("src", "app")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Requests() = %v, want %v", got, want)
+	}
+}
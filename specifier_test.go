@@ -101,6 +101,17 @@ func TestParseSpecifier(t *testing.T) {
 				Name:  "@org/repo",
 			},
 		},
+		{
+			name:  "package with file path",
+			input: "pkgname/dist/file.min.js",
+			want: &Specifier{
+				Proto: "",
+				Scope: "",
+				Pkg:   "pkgname",
+				Path:  "dist/file.min.js",
+				Name:  "pkgname",
+			},
+		},
 		{
 			name:    "missing package name after slash",
 			input:   "@scope/",
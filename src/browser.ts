@@ -0,0 +1,55 @@
+import {
+  type AsyncFS,
+  type AsyncOptions,
+  type AsyncResolver,
+  type FS,
+  type Options,
+  type Resolver,
+  instantiate,
+  normalizeOptions,
+} from "./shared.js";
+import { isCoreModule } from "./builtins.js";
+import { dirname as dir, join } from "./posix.js";
+import { Go } from "./wasm_exec.js";
+
+const go = new Go();
+let loading: Promise<void> | undefined;
+const init = () => {
+  loading ??= (async () => {
+    const response = fetch(new URL("main.wasm", import.meta.url));
+    let result: WebAssembly.WebAssemblyInstantiatedSource;
+    try {
+      result = await WebAssembly.instantiateStreaming(response, go.importObject);
+    } catch {
+      // Servers without the application/wasm MIME type break streaming compilation.
+      const buffer = await (await response).arrayBuffer();
+      result = await WebAssembly.instantiate(buffer, go.importObject);
+    }
+    go.run(result.instance);
+  })();
+  loading.catch(() => {
+    loading = undefined;
+  });
+  return loading;
+};
+
+const host = {
+  isCoreModule,
+  path: {
+    dir,
+    join,
+  },
+};
+
+export type { AsyncOptions, AsyncResolver, Options, Resolver, Specifier } from "./shared.js";
+export { builtinModules } from "./builtins.js";
+
+/**
+ * Creates a resolver in browsers and web workers, where `fs` must be supplied.
+ */
+export function createResolve(options: AsyncOptions & { fs: AsyncFS }): Promise<AsyncResolver>;
+export function createResolve(options: Options & { fs: FS }): Promise<Resolver>;
+export async function createResolve(options: Options): Promise<Resolver | AsyncResolver> {
+  await init();
+  return instantiate(normalizeOptions(options, host));
+}
@@ -1,7 +1,15 @@
 import { join } from "node:path";
 import { readFileSync } from "node:fs";
-import { type Options, normalizeOptions } from "./shared.js";
-import { goGlobal, Go } from "./wasm_exec.js";
+import {
+  type AsyncOptions,
+  type AsyncResolver,
+  type Options,
+  type Resolver,
+  instantiate,
+  normalizeOptions,
+} from "./shared.js";
+import { host } from "./node.js";
+import { Go } from "./wasm_exec.js";
 
 const go = new Go();
 let loaded = false;
@@ -16,9 +24,11 @@ const init = async () => {
   loaded = true;
 };
 
-export const createResolve = async (
-  options?: Options,
-): Promise<(req: string, dir: string) => string> => {
+export type { AsyncOptions, AsyncResolver, Options, Resolver, Specifier } from "./shared.js";
+
+export function createResolve(options: AsyncOptions): Promise<AsyncResolver>;
+export function createResolve(options?: Options): Promise<Resolver>;
+export async function createResolve(options?: Options): Promise<Resolver | AsyncResolver> {
   await init();
-  return goGlobal["@startracex/node-resolve"](normalizeOptions(options));
-};
+  return instantiate(normalizeOptions(options, host));
+}
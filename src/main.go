@@ -1,9 +1,12 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall/js"
 	"time"
 
@@ -26,10 +29,24 @@ func (v *jsFileInfo) Sys() any           { return nil }
 
 type jsFS struct {
 	jsObj js.Value
+	// async awaits the Promises returned by the JS functions. It must only be set
+	// when the FS is used off the goroutine of a JS callback.
+	async bool
+}
+
+func (f jsFS) call(method string, path string) (js.Value, error) {
+	result, err := call(f.jsObj, method, path)
+	if err != nil || !f.async {
+		return result, err
+	}
+	return await(result)
 }
 
 func (f jsFS) Stat(path string) (fs.FileInfo, error) {
-	result := f.jsObj.Call("stat", path)
+	result, err := f.call("stat", path)
+	if err != nil {
+		return nil, err
+	}
 	if !result.Get("exists").Bool() {
 		return nil, os.ErrNotExist
 	}
@@ -42,7 +59,93 @@ func (f jsFS) Stat(path string) (fs.FileInfo, error) {
 }
 
 func (f jsFS) ReadFile(path string) ([]byte, error) {
-	return []byte(f.jsObj.Call("readFile", path).String()), nil
+	result, err := f.call("readFile", path)
+	if err != nil {
+		return nil, err
+	}
+	if result.Type() != js.TypeString {
+		return nil, &fs.PathError{Op: "read", Path: path, Err: errors.New("readFile did not return a string")}
+	}
+	return []byte(result.String()), nil
+}
+
+// call invokes a JS method, turning a thrown exception into an error.
+func call(obj js.Value, method string, args ...any) (result js.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+		}
+	}()
+	return obj.Call(method, args...), nil
+}
+
+// await blocks until v settles if it is a thenable, and returns it unchanged
+// otherwise. Blocking on the goroutine of a JS callback would deadlock.
+func await(v js.Value) (js.Value, error) {
+	if v.Type() != js.TypeObject || v.Get("then").Type() != js.TypeFunction {
+		return v, nil
+	}
+
+	var result js.Value
+	var err error
+	done := make(chan struct{})
+	onFulfilled := js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) > 0 {
+			result = args[0]
+		}
+		close(done)
+		return nil
+	})
+	defer onFulfilled.Release()
+	onRejected := js.FuncOf(func(this js.Value, args []js.Value) any {
+		reason := js.Undefined()
+		if len(args) > 0 {
+			reason = args[0]
+		}
+		err = newThrownError(reason)
+		close(done)
+		return nil
+	})
+	defer onRejected.Release()
+
+	v.Call("then", onFulfilled, onRejected)
+	<-done
+	return result, err
+}
+
+func recoveredError(r any) error {
+	if err, ok := r.(js.Error); ok {
+		return newThrownError(err.Value)
+	}
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
+
+// thrownError is a value thrown or rejected by JS, which may be any value rather
+// than an Error object.
+type thrownError struct {
+	value js.Value
+	msg   string
+}
+
+func newThrownError(value js.Value) *thrownError {
+	return &thrownError{value: value, msg: "JavaScript error: " + jsString(value)}
+}
+
+func (e *thrownError) Error() string {
+	return e.msg
+}
+
+// jsString converts v like String(v), which may itself throw.
+func jsString(v js.Value) (s string) {
+	defer func() {
+		if recover() != nil {
+			s = "<" + v.Type().String() + ">"
+		}
+	}()
+	return js.Global().Call("String", v).String()
 }
 
 type jsPath struct {
@@ -95,31 +198,248 @@ func toStringSliceMap(jsObj js.Value) map[string][]string {
 	return result
 }
 
-func JSResolve(this js.Value, args []js.Value) any {
+func toJSArray(values []string) []any {
+	result := make([]any, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+func toGoValue(jsVal js.Value) any {
+	if jsVal.IsUndefined() || jsVal.IsNull() {
+		return nil
+	}
+	// Parse with key order kept, which decides the precedence of conditions.
+	data := js.Global().Get("JSON").Call("stringify", jsVal).String()
+	result, err := resolve.ParseJSON([]byte(data))
+	if err != nil {
+		return nil
+	}
+	return result
+}
+
+// jsError converts err to a JS Error, keeping thrown JS Errors and the code of
+// resolution failures.
+func jsError(err error) js.Value {
+	var resolveErr *resolve.ResolveError
+	var thrown *thrownError
+	switch {
+	case errors.As(err, &resolveErr):
+		e := newJSError(err.Error(), resolveErr.Code)
+		if errors.As(err, &thrown) {
+			e.Set("cause", thrown.value)
+		}
+		return e
+	case errors.As(err, &thrown):
+		if thrown.value.InstanceOf(js.Global().Get("Error")) {
+			return thrown.value
+		}
+		// The JS wrapper only throws Error objects.
+		e := newJSError(err.Error(), "ERR_INTERNAL")
+		e.Set("cause", thrown.value)
+		return e
+	}
+	return newJSError(err.Error(), "ERR_INTERNAL")
+}
+
+func newJSError(message, code string) js.Value {
+	e := js.Global().Get("Error").New(message)
+	e.Set("code", code)
+	return e
+}
+
+// errorValue is jsError falling back to a generic Error should the conversion panic.
+func errorValue(err error) (v js.Value) {
+	defer func() {
+		if recover() != nil {
+			v = newJSError("resolve: internal error", "ERR_INTERNAL")
+		}
+	}()
+	return jsError(err)
+}
+
+type method func(args []js.Value) (any, error)
+
+// invoke runs fn, recovering panics, and converts its error to a JS value; ok is
+// false when result is such an error.
+func invoke(fn method, args []js.Value) (result any, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, ok = errorValue(recoveredError(r)), false
+		}
+	}()
+	result, err := fn(args)
+	if err != nil {
+		return errorValue(err), false
+	}
+	return result, true
+}
+
+// guard recovers panics at the JS boundary and returns errors as JS Error values,
+// which the JS wrapper throws.
+func guard(fn method) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		result, _ := invoke(fn, args)
+		return result
+	})
+}
+
+// guardAsync runs fn on its own goroutine, so that it may await Promises, and
+// returns a Promise settling with its result.
+func guardAsync(fn method) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		executor := js.FuncOf(func(this js.Value, callbacks []js.Value) any {
+			resolveFn, rejectFn := callbacks[0], callbacks[1]
+			go func() {
+				result, ok := invoke(fn, args)
+				if !ok {
+					rejectFn.Invoke(result)
+					return
+				}
+				resolveFn.Invoke(result)
+			}()
+			return nil
+		})
+		defer executor.Release()
+		return js.Global().Get("Promise").New(executor)
+	})
+}
+
+func JSResolve(args []js.Value) (any, error) {
 	arg0 := args[0]
-	fs := jsFS{jsObj: arg0.Get("fs")}
+	async := arg0.Get("async").Truthy()
+	fs := jsFS{jsObj: arg0.Get("fs"), async: async}
 	path := jsPath{jsObj: arg0.Get("path")}
 	resolver := resolve.NewModuleResolver(&resolve.ResolverConfig{
-		Extensions:           toStringSlice(arg0.Get("extensions")),
-		ExtensionMap:         toStringSliceMap(arg0.Get("extensionMap")),
-		ModulesDirectoryName: arg0.Get("modulesDirectoryName").String(),
-		ManifestFileName:     arg0.Get("manifestFileName").String(),
-		MainFields:           toStringSlice(arg0.Get("mainFields")),
-		IndexName:            arg0.Get("indexName").String(),
-		Conditions:           toStringSlice(arg0.Get("conditions")),
-		FS:                   fs,
-		Path:                 path,
+		Extensions:            toStringSlice(arg0.Get("extensions")),
+		ExtensionMap:          toStringSliceMap(arg0.Get("extensionMap")),
+		ExtensionMapFirst:     arg0.Get("extensionMapFirst").Truthy(),
+		ModulesDirectoryName:  arg0.Get("modulesDirectoryName").String(),
+		ManifestFileName:      arg0.Get("manifestFileName").String(),
+		MainFields:            toStringSlice(arg0.Get("mainFields")),
+		MainFieldConditions:   toStringSliceMap(arg0.Get("mainFieldConditions")),
+		IndexName:             arg0.Get("indexName").String(),
+		IndexNames:            toStringSlice(arg0.Get("indexNames")),
+		DisableDirectoryIndex: arg0.Get("disableDirectoryIndex").Truthy(),
+		Conditions:            toStringSlice(arg0.Get("conditions")),
+		ProbeFallbacks:        arg0.Get("probeFallbacks").Truthy(),
+		AdditionalModulePaths: toStringSlice(arg0.Get("additionalModulePaths")),
+		FS:                    fs,
+		Path:                  path,
 		IsCoreModule: func(s string) bool {
 			return arg0.Call("isCoreModule", s).Bool()
 		},
 	})
 
-	return js.FuncOf(func(this js.Value, args []js.Value) any {
-		return resolver.Resolve(args[0].String(), args[1].String())
+	wrap := guard
+	if async {
+		wrap = guardAsync
+	}
+	methods := resolverMethods(resolver)
+	resolveFn := wrap(methods["resolve"])
+	funcs := []js.Func{resolveFn}
+	for name, method := range methods {
+		fn := wrap(method)
+		resolveFn.Set(name, fn)
+		funcs = append(funcs, fn)
+	}
+
+	// dispose releases the functions of the resolver, itself included; calling any
+	// of them afterwards is fatal to the runtime, which the JS wrapper prevents.
+	var dispose js.Func
+	dispose = guard(func(args []js.Value) (any, error) {
+		for _, fn := range funcs {
+			fn.Release()
+		}
+		dispose.Release()
+		resolver.ClearCache()
+		return nil, nil
 	})
+	resolveFn.Set("dispose", dispose)
+
+	return resolveFn, nil
+}
+
+func resolverMethods(resolver *resolve.ModuleResolver) map[string]method {
+	return map[string]method{
+		"resolve": func(args []js.Value) (any, error) {
+			return resolver.TryResolve(args[0].String(), args[1].String())
+		},
+		"resolveImports": func(args []js.Value) (any, error) {
+			specifier := args[0].String()
+			if !strings.HasPrefix(specifier, "#") {
+				return nil, &resolve.ResolveError{
+					Code:      resolve.CodePackageImportNotDefined,
+					Specifier: specifier,
+					Base:      args[1].String(),
+				}
+			}
+			return resolver.TryResolve(specifier, args[1].String())
+		},
+		"resolveFrom": func(args []js.Value) (any, error) {
+			specifier, paths := args[0].String(), toStringSlice(args[1])
+			resolved, root := resolver.ResolveFrom(specifier, paths)
+			if resolved == "" {
+				return nil, &resolve.ResolveError{
+					Code:      resolve.CodeModuleNotFound,
+					Specifier: specifier,
+					Base:      strings.Join(paths, ", "),
+				}
+			}
+			return map[string]any{"resolved": resolved, "root": root}, nil
+		},
+		"findUp": func(args []js.Value) (any, error) {
+			found, err := resolver.FindUp(args[0].String(), args[1].String())
+			if err != nil {
+				return nil, nil
+			}
+			return found, nil
+		},
+		"modulesPaths": func(args []js.Value) (any, error) {
+			return toJSArray(resolver.ModulesPaths(args[0].String(), args[1].String())), nil
+		},
+		"resolveExports": func(args []js.Value) (any, error) {
+			conditions := resolver.Config.Conditions
+			if len(args) > 2 {
+				if c := toStringSlice(args[2]); c != nil {
+					conditions = c
+				}
+			}
+			subpathResolver := resolve.NewSubpathResolver(resolve.SubpathResolverConfig{
+				Exports:        toGoValue(args[0]),
+				Conditions:     conditions,
+				ProbeFallbacks: resolver.Config.ProbeFallbacks,
+			})
+			return toJSArray(subpathResolver.ResolveExports(args[1].String())), nil
+		},
+		"parseSpecifier": func(args []js.Value) (any, error) {
+			spec, err := resolve.NewSpecifier(args[0].String())
+			if err != nil {
+				return nil, nil
+			}
+			return map[string]any{
+				"proto": spec.Proto,
+				"scope": spec.Scope,
+				"pkg":   spec.Pkg,
+				"name":  spec.Name,
+				"path":  spec.Path,
+			}, nil
+		},
+		"format": func(args []js.Value) (any, error) {
+			return resolver.Format(args[0].String()), nil
+		},
+		"specifierFor": func(args []js.Value) (any, error) {
+			specifier, err := resolver.SpecifierFor(args[0].String(), args[1].String())
+			if err != nil {
+				return nil, nil
+			}
+			return specifier, nil
+		},
+	}
 }
 
 func main() {
-	js.Global().Set("@startracex/node-resolve", js.FuncOf(JSResolve))
+	js.Global().Set("@startracex/node-resolve", guard(JSResolve))
 	select {}
 }
@@ -0,0 +1,73 @@
+import { readFileSync, statSync } from "node:fs";
+import { readFile, stat } from "node:fs/promises";
+import { dirname as dir, join } from "node:path";
+import { builtinModules } from "node:module";
+import type { AsyncFS, FS, Host } from "./shared.js";
+
+const isNodeProto = (id: string) => id.startsWith("node:");
+
+const coreModuleSet = new Set(
+  builtinModules
+    .filter((id) => !isNodeProto(id))
+    .map((id) => {
+      const index = id.indexOf("/");
+      return index === -1 ? id : id.substring(0, index);
+    }),
+);
+
+const isCoreModule = (id: string) => isNodeProto(id) || coreModuleSet.has(id);
+
+const notExist = {
+  exists: false,
+  isDir: false,
+  size: 0,
+  mtime: 0,
+};
+
+const fs: FS = {
+  stat: (path) => {
+    try {
+      const info = statSync(path);
+      return {
+        exists: true,
+        isDir: info.isDirectory(),
+        size: info.size,
+        mtime: info.mtimeMs,
+      };
+    } catch {
+      return notExist;
+    }
+  },
+  readFile: (path) => {
+    return readFileSync(path, "utf-8");
+  },
+};
+
+const asyncFs: AsyncFS = {
+  stat: async (path) => {
+    try {
+      const info = await stat(path);
+      return {
+        exists: true,
+        isDir: info.isDirectory(),
+        size: info.size,
+        mtime: info.mtimeMs,
+      };
+    } catch {
+      return notExist;
+    }
+  },
+  readFile: (path) => {
+    return readFile(path, "utf-8");
+  },
+};
+
+export const host: Host = {
+  isCoreModule,
+  path: {
+    dir,
+    join,
+  },
+  fs,
+  asyncFs,
+};
@@ -0,0 +1,56 @@
+/**
+ * A pure-JS subset of `node:path/posix`, for hosts without `node:path`.
+ */
+
+export const normalize = (path: string): string => {
+  if (path === "") {
+    return ".";
+  }
+  const absolute = path.startsWith("/");
+  const segments: string[] = [];
+  for (const segment of path.split("/")) {
+    if (segment === "" || segment === ".") {
+      continue;
+    }
+    if (segment === "..") {
+      if (segments.length > 0 && segments[segments.length - 1] !== "..") {
+        segments.pop();
+      } else if (!absolute) {
+        segments.push(segment);
+      }
+      continue;
+    }
+    segments.push(segment);
+  }
+  let joined = segments.join("/");
+  if (joined !== "" && path.endsWith("/")) {
+    joined += "/";
+  }
+  if (absolute) {
+    return "/" + joined;
+  }
+  return joined || ".";
+};
+
+export const join = (...paths: string[]): string => {
+  const joined = paths.filter((path) => path !== "").join("/");
+  return normalize(joined);
+};
+
+export const dirname = (path: string): string => {
+  if (path === "") {
+    return ".";
+  }
+  let end = path.length;
+  while (end > 1 && path[end - 1] === "/") {
+    end--;
+  }
+  const index = path.lastIndexOf("/", end - 1);
+  if (index === -1) {
+    return ".";
+  }
+  if (index === 0) {
+    return "/";
+  }
+  return path.substring(0, index);
+};
@@ -1,100 +1,200 @@
-import { readFileSync, statSync } from "node:fs";
-import { dirname as dir, join } from "node:path";
-import { builtinModules } from "node:module";
-
-const isNodeProto = (id: string) => id.startsWith("node:");
-
-const coreModuleSet = new Set(
-  builtinModules
-    .filter((id) => !isNodeProto(id))
-    .map((id) => {
-      const index = id.indexOf("/");
-      return index === -1 ? id : id.substring(0, index);
-    }),
-);
-
-const _isCoreModule = (id: string) => isNodeProto(id) || coreModuleSet.has(id);
-
-const _fs: {
-  stat: (path) => {
-    exists: boolean;
-    isDir: boolean;
-    size: number;
-    mtime: number;
-  };
-  readFile: (path) => string;
-} = {
-  stat: (path) => {
-    try {
-      const info = statSync(path);
-      return {
-        exists: true,
-        isDir: info.isDirectory(),
-        size: info.size,
-        mtime: info.mtimeMs,
-      };
-    } catch {
-      return {
-        exists: false,
-        isDir: false,
-        size: 0,
-        mtime: 0,
-      };
-    }
-  },
-  readFile: (path) => {
-    return readFileSync(path, "utf-8");
-  },
+import { goGlobal } from "./wasm_exec.js";
+
+export type Stat = {
+  exists: boolean;
+  isDir: boolean;
+  size: number;
+  mtime: number;
+};
+
+export type FS = {
+  stat: (path: string) => Stat;
+  readFile: (path: string) => string;
 };
 
-const _path: {
+export type AsyncFS = {
+  stat: (path: string) => Promise<Stat>;
+  readFile: (path: string) => Promise<string>;
+};
+
+export type Path = {
   dir: (path: string) => string;
   join: (...paths: string[]) => string;
-} = {
-  dir,
-  join,
+};
+
+/**
+ * The defaults an entry point supplies for the host it runs on.
+ */
+export type Host = {
+  isCoreModule: (id: string) => boolean;
+  path: Path;
+  fs?: FS;
+  asyncFs?: AsyncFS;
 };
 
 const _default = "default";
 
 export type Options = {
+  /**
+   * Await the Promises returned by `fs`; every resolver method then returns a Promise.
+   */
+  async?: boolean;
   extensions?: string[];
   extensionMap?: {};
+  extensionMapFirst?: boolean;
   mainFields?: string[];
+  mainFieldConditions?: Record<string, string[]>;
   conditions?: string[];
+  probeFallbacks?: boolean;
   indexName?: string;
+  indexNames?: string[];
+  disableDirectoryIndex?: boolean;
   modulesDirectoryName?: string;
   manifestFileName?: string;
+  additionalModulePaths?: string[];
   isCoreModule?: (id: any) => boolean;
-  path?: typeof _path;
-  fs?: typeof _fs;
+  path?: Path;
+  fs?: FS | AsyncFS;
 };
 
-export const normalizeOptions = ({
-  extensions = [".js"],
-  extensionMap = {},
-  mainFields = ["main"],
-  conditions = [_default],
-  indexName = "index",
-  modulesDirectoryName = "node_modules",
-  manifestFileName = "package.json",
-  isCoreModule = _isCoreModule,
-  path = _path,
-  fs = _fs,
-}: Options = {}): Options => {
+export type AsyncOptions = Omit<Options, "async" | "fs"> & {
+  async: true;
+  fs?: AsyncFS;
+};
+
+export type Specifier = {
+  proto: string;
+  scope: string;
+  pkg: string;
+  name: string;
+  path: string;
+};
+
+/**
+ * Resolution failures throw an `Error` whose `code` is a Node error code such as
+ * `ERR_MODULE_NOT_FOUND`, `ERR_PACKAGE_PATH_NOT_EXPORTED` or `ERR_PACKAGE_IMPORT_NOT_DEFINED`.
+ */
+export type Resolver = {
+  (req: string, dir: string): string;
+  resolve(req: string, dir: string): string;
+  resolveImports(req: string, dir: string): string;
+  resolveFrom(req: string, paths: string[]): { resolved: string; root: string };
+  findUp(dir: string, name: string): string | null;
+  modulesPaths(start: string, name: string): string[];
+  resolveExports(exports: unknown, entry: string, conditions?: string[]): string[];
+  parseSpecifier(input: string): Specifier | null;
+  format(path: string): string;
+  specifierFor(target: string, dir: string): string | null;
+  /**
+   * Releases the functions and caches held by the wasm runtime for this resolver. A resolver
+   * lives until it is disposed, even when unreachable; afterwards every method throws
+   * `ERR_RESOLVER_DISPOSED`. Calls still pending in async mode settle normally.
+   */
+  dispose(): void;
+};
+
+type Promisify<T> = {
+  [K in keyof T]: T[K] extends (...args: infer A) => infer R ? (...args: A) => Promise<R> : T[K];
+};
+
+/**
+ * The resolver of async mode, whose methods return Promises rejecting as {@link Resolver} throws.
+ */
+export type AsyncResolver = ((req: string, dir: string) => Promise<string>) &
+  Promisify<Omit<Resolver, "dispose">> &
+  Pick<Resolver, "dispose">;
+
+export const normalizeOptions = (
+  {
+    async = false,
+    extensions = [".js"],
+    extensionMap = {},
+    extensionMapFirst = false,
+    mainFields = ["main"],
+    mainFieldConditions = {},
+    conditions = [_default],
+    probeFallbacks = false,
+    indexName = "index",
+    indexNames = [indexName],
+    disableDirectoryIndex = false,
+    modulesDirectoryName = "node_modules",
+    manifestFileName = "package.json",
+    additionalModulePaths = [],
+    isCoreModule,
+    path,
+    fs,
+  }: Options = {},
+  host: Host,
+): Options => {
+  fs ??= async ? host.asyncFs : host.fs;
+  if (!fs) {
+    throw new TypeError("options.fs is required on this host");
+  }
   if (!conditions.includes(_default)) {
     conditions.push(_default);
   }
   return {
+    async,
     extensions,
     extensionMap,
+    extensionMapFirst,
     mainFields,
+    mainFieldConditions,
     conditions,
+    probeFallbacks,
     indexName,
+    indexNames,
+    disableDirectoryIndex,
     modulesDirectoryName,
     manifestFileName,
-    path,
+    additionalModulePaths,
+    path: path ?? host.path,
     fs,
-    isCoreModule,
+    isCoreModule: isCoreModule ?? host.isCoreModule,
   };
 };
+
+const unwrap = <T>(value: T | Error): T => {
+  if (value instanceof Error) {
+    throw value;
+  }
+  return value;
+};
+
+const disposedError = () => {
+  const error = new Error("resolve: resolver is disposed");
+  (error as Error & { code: string }).code = "ERR_RESOLVER_DISPOSED";
+  return error;
+};
+
+const wrapResolver = <T extends Resolver | AsyncResolver>(resolver: T, async: boolean): T => {
+  let disposed = false;
+  const guard =
+    (method: Function) =>
+    (...args: unknown[]) => {
+      if (disposed) {
+        if (async) {
+          return Promise.reject(disposedError());
+        }
+        throw disposedError();
+      }
+      return unwrap(method(...args));
+    };
+  const wrapped = guard(resolver) as T;
+  for (const [name, method] of Object.entries(resolver)) {
+    wrapped[name] = guard(method);
+  }
+  wrapped.dispose = () => {
+    if (!disposed) {
+      disposed = true;
+      resolver.dispose();
+    }
+  };
+  return wrapped;
+};
+
+/**
+ * Creates a resolver from normalized options; the wasm module must be running.
+ */
+export const instantiate = (options: Options): Resolver | AsyncResolver =>
+  wrapResolver(unwrap(goGlobal["@startracex/node-resolve"](options)), options.async);
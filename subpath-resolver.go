@@ -1,10 +1,24 @@
 package resolve
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
 	"strings"
 )
 
+var ErrInvalidExports = errors.New("resolve: invalid exports")
+
 func NormalizeMapping(m any) map[string]any {
+	if v, ok := m.(*JSONValue); ok {
+		if v.Kind != JSONObject {
+			return NormalizeMapping(jsonTree(v))
+		}
+		return jsonMapping(v)
+	}
+
 	if m == nil {
 		return make(map[string]any)
 	}
@@ -17,6 +31,10 @@ func NormalizeMapping(m any) map[string]any {
 		return map[string]any{".": arr}
 	}
 
+	if arr, ok := m.([]any); ok {
+		return map[string]any{".": arr}
+	}
+
 	if mMap, ok := m.(map[string]any); ok {
 		return mMap
 	}
@@ -24,6 +42,61 @@ func NormalizeMapping(m any) map[string]any {
 	return make(map[string]any)
 }
 
+// NormalizeExports normalizes an exports field to a subpath mapping. Strings, arrays
+// and objects whose keys are all conditions are sugar for the "." entry; objects
+// mixing subpath keys and condition keys are rejected with ErrInvalidExports.
+// Objects given as *JSONValue keep their key order, which decides the precedence
+// of conditions.
+func NormalizeExports(exports any) (map[string]any, error) {
+	var keys []string
+	switch v := exports.(type) {
+	case nil:
+		return make(map[string]any), nil
+	case string, []string, []any:
+		return map[string]any{".": v}, nil
+	case *JSONValue:
+		if v.Kind != JSONObject {
+			return NormalizeExports(jsonTree(v))
+		}
+		keys = v.Keys
+	case map[string]any:
+		keys = slices.Collect(maps.Keys(v))
+	default:
+		return nil, fmt.Errorf("%w: unsupported type %T", ErrInvalidExports, exports)
+	}
+
+	var subpaths, conditions []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, ".") {
+			subpaths = append(subpaths, key)
+		} else {
+			conditions = append(conditions, key)
+		}
+	}
+	if len(subpaths) > 0 && len(conditions) > 0 {
+		slices.Sort(subpaths)
+		slices.Sort(conditions)
+		return nil, fmt.Errorf("%w: %q mixes subpath keys and condition keys", ErrInvalidExports, append(subpaths, conditions...))
+	}
+	if len(conditions) > 0 {
+		return map[string]any{".": exports}, nil
+	}
+	if v, ok := exports.(*JSONValue); ok {
+		return jsonMapping(v), nil
+	}
+	return exports.(map[string]any), nil
+}
+
+// jsonMapping converts a subpath object to a mapping whose targets keep the key
+// order of their conditions.
+func jsonMapping(v *JSONValue) map[string]any {
+	mapping := make(map[string]any, len(v.Keys))
+	for i, key := range v.Keys {
+		mapping[key] = jsonTree(v.Values[i])
+	}
+	return mapping
+}
+
 type match struct {
 	key         string
 	replacement string
@@ -67,6 +140,8 @@ func resolveMappingValue(value any, conditions []string) []string {
 	switch v := value.(type) {
 	case string:
 		return []string{v}
+	case []string:
+		return v
 	case []any:
 		var result []string
 		for _, item := range v {
@@ -76,20 +151,26 @@ func resolveMappingValue(value any, conditions []string) []string {
 			}
 		}
 		return result
-	case map[string]any:
-		for _, cond := range conditions {
-			if sub, exists := v[cond]; exists {
-				return resolveMappingValue(sub, conditions)
-			}
-		}
 	}
 
+	keys, values, _ := conditionEntries(value)
+	for i, key := range keys {
+		if slices.Contains(conditions, key) {
+			return resolveMappingValue(values[i], conditions)
+		}
+	}
 	return nil
 }
 
 func resolveMapping(mapping map[string]any, conditions []string, input string) []string {
-	if value, ok := mapping[input]; ok {
+	return matchMapping(mapping, input, func(value any) []string {
 		return resolveMappingValue(value, conditions)
+	})
+}
+
+func matchMapping(mapping map[string]any, input string, resolveValue func(any) []string) []string {
+	if value, ok := mapping[input]; ok {
+		return resolveValue(value)
 	}
 
 	key, wildcard, ok := findWildcardMatch(mapping, input)
@@ -98,7 +179,7 @@ func resolveMapping(mapping map[string]any, conditions []string, input string) [
 	}
 
 	value := mapping[key]
-	resolved := resolveMappingValue(value, conditions)
+	resolved := resolveValue(value)
 	if resolved == nil {
 		return nil
 	}
@@ -115,6 +196,90 @@ func resolveMapping(mapping map[string]any, conditions []string, input string) [
 	return result
 }
 
+type targetResult int
+
+const (
+	targetUndefined targetResult = iota
+	targetNull
+	targetInvalid
+	targetValid
+)
+
+// resolveTargetValue selects a single target like Node's PACKAGE_TARGET_RESOLVE:
+// array entries are tried until one is a valid target, regardless of whether the
+// file exists, and the keys of a conditions object are tried in order, a matching
+// condition whose value yields nothing falling through to the next key.
+func resolveTargetValue(value any, conditions []string, imports bool) (string, targetResult) {
+	switch v := value.(type) {
+	case nil:
+		return "", targetNull
+	case string:
+		if !ValidTarget(v, imports) {
+			return "", targetInvalid
+		}
+		return v, targetValid
+	case []string:
+		for _, item := range v {
+			if ValidTarget(item, imports) {
+				return item, targetValid
+			}
+		}
+		return "", targetNull
+	case []any:
+		for _, item := range v {
+			if target, result := resolveTargetValue(item, conditions, imports); result == targetValid {
+				return target, result
+			}
+		}
+		return "", targetNull
+	}
+
+	keys, values, ok := conditionEntries(value)
+	if !ok {
+		return "", targetInvalid
+	}
+	for i, key := range keys {
+		if !slices.Contains(conditions, key) {
+			continue
+		}
+		target, result := resolveTargetValue(values[i], conditions, imports)
+		if result == targetUndefined {
+			continue
+		}
+		return target, result
+	}
+	return "", targetUndefined
+}
+
+var invalidSegments = []string{"", ".", "..", "node_modules"}
+
+// ValidTarget reports whether target may be used as an exports or imports target.
+// Exports targets must start with "./"; imports targets may also be bare specifiers
+// or node: builtins. Relative targets must not contain empty, "." or ".." segments,
+// nor node_modules.
+func ValidTarget(target string, imports bool) bool {
+	if !strings.HasPrefix(target, subpathPrefix) {
+		if !imports || strings.HasPrefix(target, "../") || strings.HasPrefix(target, "/") {
+			return false
+		}
+		if scheme, _, ok := strings.Cut(target, ":"); ok && !strings.ContainsAny(scheme, "/@") {
+			return scheme == "node"
+		}
+		return true
+	}
+
+	subpath := strings.ReplaceAll(target[len(subpathPrefix):], "\\", "/")
+	for _, segment := range strings.Split(subpath, "/") {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+		if slices.Contains(invalidSegments, strings.ToLower(segment)) {
+			return false
+		}
+	}
+	return true
+}
+
 const subpathPrefix = "./"
 
 func normalizeEntry(entry string) string {
@@ -131,12 +296,20 @@ type SubpathResolver struct {
 	Conditions []string
 	Exports    map[string]any
 	Imports    map[string]any
+	// ProbeFallbacks returns every target of array fallbacks, leaving the choice to
+	// whoever probes the disk, instead of only the first valid target.
+	ProbeFallbacks bool
 }
 
+// SubpathResolverConfig configures NewSubpathResolver. Exports and Imports may be
+// given as a *JSONValue, as returned by Manifest, to keep the key order of their
+// conditions objects; maps have no order, so their conditions are tried by name
+// with "default" last.
 type SubpathResolverConfig struct {
-	Exports    any
-	Imports    any
-	Conditions []string
+	Exports        any
+	Imports        any
+	Conditions     []string
+	ProbeFallbacks bool
 }
 
 func NewSubpathResolver(config SubpathResolverConfig) *SubpathResolver {
@@ -145,10 +318,16 @@ func NewSubpathResolver(config SubpathResolverConfig) *SubpathResolver {
 		conditions = []string{"default"}
 	}
 
+	exports, err := NormalizeExports(config.Exports)
+	if err != nil {
+		exports = make(map[string]any)
+	}
+
 	return &SubpathResolver{
-		Conditions: conditions,
-		Exports:    NormalizeMapping(config.Exports),
-		Imports:    NormalizeMapping(config.Imports),
+		Conditions:     conditions,
+		Exports:        exports,
+		Imports:        NormalizeMapping(config.Imports),
+		ProbeFallbacks: config.ProbeFallbacks,
 	}
 }
 
@@ -156,12 +335,171 @@ func (r *SubpathResolver) ResolveExports(entry string) []string {
 	if r.Exports == nil {
 		return nil
 	}
-	return resolveMapping(r.Exports, r.Conditions, normalizeEntry(entry))
+	return r.resolve(r.Exports, normalizeEntry(entry), false)
 }
 
 func (r *SubpathResolver) ResolveImports(entry string) []string {
 	if r.Imports == nil {
 		return nil
 	}
-	return resolveMapping(r.Imports, r.Conditions, entry)
+	return r.resolve(r.Imports, entry, true)
+}
+
+func (r *SubpathResolver) resolve(mapping map[string]any, entry string, imports bool) []string {
+	if r.ProbeFallbacks {
+		return resolveMapping(mapping, r.Conditions, entry)
+	}
+
+	resolved := matchMapping(mapping, entry, func(value any) []string {
+		if target, result := resolveTargetValue(value, r.Conditions, imports); result == targetValid {
+			return []string{target}
+		}
+		return nil
+	})
+	if len(resolved) == 1 && !ValidTarget(resolved[0], imports) {
+		return nil
+	}
+	return resolved
+}
+
+// ExportEntry is a subpath of an exports mapping with its targets per condition.
+// Pattern is the key a subpath was expanded from, and Blocked marks null targets.
+type ExportEntry struct {
+	Subpath string
+	Pattern string
+	Targets map[string][]string
+	Blocked bool
+}
+
+// ListExports lists every exports key, sorted, with the targets reached under each
+// of conditions together with "default". Pattern keys are listed unexpanded.
+func (r *SubpathResolver) ListExports(conditions []string) []ExportEntry {
+	if conditions == nil {
+		conditions = r.Conditions
+	}
+
+	keys := slices.Sorted(maps.Keys(r.Exports))
+	entries := make([]ExportEntry, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, r.exportEntry(key, conditions))
+	}
+	return entries
+}
+
+func (r *SubpathResolver) exportEntry(subpath string, conditions []string) ExportEntry {
+	entry := ExportEntry{Subpath: subpath}
+	if value, ok := r.Exports[subpath]; ok && value == nil {
+		entry.Blocked = true
+		return entry
+	}
+
+	for _, cond := range conditions {
+		active := []string{cond}
+		if cond != "default" {
+			active = append(active, "default")
+		}
+		resolver := &SubpathResolver{
+			Conditions:     active,
+			Exports:        r.Exports,
+			ProbeFallbacks: r.ProbeFallbacks,
+		}
+		if targets := resolver.ResolveExports(subpath); len(targets) > 0 {
+			if entry.Targets == nil {
+				entry.Targets = make(map[string][]string)
+			}
+			entry.Targets[cond] = targets
+		}
+	}
+	return entry
+}
+
+// ConditionTarget is a target reachable through a condition tree together with the
+// conditions leading to it, outermost first. Blocked marks null targets.
+type ConditionTarget struct {
+	Conditions []string
+	Target     string
+	Blocked    bool
+}
+
+// ExportsMatrix walks the condition tree of the exports entry and returns every
+// reachable target, whatever the active conditions are. Conditions are visited in
+// the order resolution tries them; conditions after "default" are unreachable and
+// skipped.
+func (r *SubpathResolver) ExportsMatrix(entry string) []ConditionTarget {
+	return r.matrix(r.Exports, normalizeEntry(entry), false)
+}
+
+// ImportsMatrix is like ExportsMatrix for the imports entry.
+func (r *SubpathResolver) ImportsMatrix(entry string) []ConditionTarget {
+	return r.matrix(r.Imports, entry, true)
+}
+
+func (r *SubpathResolver) matrix(mapping map[string]any, entry string, imports bool) []ConditionTarget {
+	value, ok := mapping[entry]
+	wildcard := ""
+	if !ok {
+		var key string
+		if key, wildcard, ok = findWildcardMatch(mapping, entry); !ok {
+			return nil
+		}
+		value = mapping[key]
+	}
+
+	var result []ConditionTarget
+	var walk func(value any, conditions []string) bool
+	walk = func(value any, conditions []string) bool {
+		switch v := value.(type) {
+		case nil:
+			result = append(result, ConditionTarget{Conditions: conditions, Blocked: true})
+			return false
+		case string:
+			if !r.ProbeFallbacks && !ValidTarget(v, imports) {
+				return false
+			}
+			if wildcard != "" {
+				v = strings.Replace(v, "*", wildcard, 1)
+			}
+			result = append(result, ConditionTarget{Conditions: conditions, Target: v})
+			return true
+		case []string:
+			items := make([]any, len(v))
+			for i, item := range v {
+				items[i] = item
+			}
+			return walk(items, conditions)
+		case []any:
+			for _, item := range v {
+				_, isString := item.(string)
+				if walk(item, conditions) && isString && !r.ProbeFallbacks {
+					return true
+				}
+			}
+			return false
+		}
+
+		keys, values, _ := conditionEntries(value)
+		for i, key := range keys {
+			walk(values[i], append(slices.Clip(conditions), key))
+			if key == "default" {
+				break
+			}
+		}
+		return false
+	}
+	walk(value, nil)
+
+	return result
+}
+
+// compareConditions orders condition keys by name with "default" last.
+func compareConditions(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "default":
+		return 1
+	case b == "default":
+		return -1
+	}
+	return strings.Compare(a, b)
 }
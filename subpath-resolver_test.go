@@ -1,7 +1,9 @@
 package resolve
 
 import (
+	"errors"
 	"reflect"
+	"slices"
 	"testing"
 )
 
@@ -26,6 +28,11 @@ func TestNormalizeMapping(t *testing.T) {
 			input: []string{"a", "b"},
 			want:  map[string]any{".": []string{"a", "b"}},
 		},
+		{
+			name:  "any slice input",
+			input: []any{"a", "b"},
+			want:  map[string]any{".": []any{"a", "b"}},
+		},
 		{
 			name: "map input",
 			input: map[string]any{
@@ -52,6 +59,71 @@ func TestNormalizeMapping(t *testing.T) {
 	}
 }
 
+func TestNormalizeExports(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   any
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name:  "nil input",
+			input: nil,
+			want:  map[string]any{},
+		},
+		{
+			name:  "string input",
+			input: "./index.js",
+			want:  map[string]any{".": "./index.js"},
+		},
+		{
+			name:  "array input",
+			input: []any{"./a.js", "./b.js"},
+			want:  map[string]any{".": []any{"./a.js", "./b.js"}},
+		},
+		{
+			name:  "conditions sugar",
+			input: map[string]any{"import": "./x.mjs", "require": "./x.cjs"},
+			want: map[string]any{
+				".": map[string]any{"import": "./x.mjs", "require": "./x.cjs"},
+			},
+		},
+		{
+			name:  "subpaths",
+			input: map[string]any{".": "./x.js", "./sub": "./sub.js"},
+			want:  map[string]any{".": "./x.js", "./sub": "./sub.js"},
+		},
+		{
+			name:    "mixed keys",
+			input:   map[string]any{".": "./x.js", "import": "./x.mjs"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported type",
+			input:   true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeExports(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidExports) {
+					t.Fatalf("NormalizeExports(%v) error = %v, want ErrInvalidExports", tt.input, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeExports(%v) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFindWildcardMatch(t *testing.T) {
 	mapping := map[string]any{
 		"a*":            1,
@@ -231,6 +303,7 @@ func TestResolveExportsAndImports(t *testing.T) {
 		Imports: map[string]any{
 			"#pkg/*": "lib/*.js",
 		},
+		ProbeFallbacks: true,
 	}
 
 	t.Run("resolve exports", func(t *testing.T) {
@@ -249,3 +322,251 @@ func TestResolveExportsAndImports(t *testing.T) {
 		}
 	})
 }
+
+func TestValidTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		imports bool
+		want    bool
+	}{
+		{"./dist/index.js", false, true},
+		{"./dist/*.js", false, true},
+		{"dist/index.js", false, false},
+		{"../outside.js", false, false},
+		{"/abs.js", false, false},
+		{"./", false, false},
+		{"./dist//index.js", false, false},
+		{"./dist/../secret.js", false, false},
+		{"./node_modules/dep/index.js", false, false},
+		{"./%2e%2e/secret.js", false, false},
+		{"https://example.com/x.js", false, false},
+		{"dep/sub", true, true},
+		{"@scope/dep", true, true},
+		{"node:fs", true, true},
+		{"https://example.com/x.js", true, false},
+		{"../outside.js", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := ValidTarget(tt.target, tt.imports); got != tt.want {
+				t.Errorf("ValidTarget(%q, %v) = %v, want %v", tt.target, tt.imports, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveFallbacks(t *testing.T) {
+	exports := map[string]any{
+		".":         []any{"https://cdn/x.js", "./missing.js", "./main.js"},
+		"./cond":    map[string]any{"import": map[string]any{"node": "./node.mjs"}, "default": "./default.js"},
+		"./blocked": []any{nil, "./fallback.js"},
+		"./feat/*":  []any{"invalid/*.js", "./feat/*.js"},
+	}
+
+	tests := []struct {
+		name  string
+		entry string
+		probe bool
+		want  []string
+	}{
+		{"first valid", ".", false, []string{"./missing.js"}},
+		{"probe", ".", true, []string{"https://cdn/x.js", "./missing.js", "./main.js"}},
+		{"undefined condition falls through", "./cond", false, []string{"./default.js"}},
+		{"null entry skipped", "./blocked", false, []string{"./fallback.js"}},
+		{"pattern", "./feat/a", false, []string{"./feat/a.js"}},
+		{"invalid substitution", "./feat/../../x", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewSubpathResolver(SubpathResolverConfig{
+				Exports:        exports,
+				Conditions:     []string{"import", "default"},
+				ProbeFallbacks: tt.probe,
+			})
+			if got := r.ResolveExports(tt.entry); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveExports(%q) = %v, want %v", tt.entry, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListExports(t *testing.T) {
+	r := NewSubpathResolver(SubpathResolverConfig{
+		Exports: map[string]any{
+			".":           map[string]any{"import": "./index.mjs", "default": "./index.cjs"},
+			"./feature/*": "./src/*.js",
+			"./internal":  nil,
+		},
+	})
+
+	got := r.ListExports([]string{"import", "require"})
+	want := []ExportEntry{
+		{Subpath: ".", Targets: map[string][]string{"import": {"./index.mjs"}, "require": {"./index.cjs"}}},
+		{Subpath: "./feature/*", Targets: map[string][]string{"import": {"./src/*.js"}, "require": {"./src/*.js"}}},
+		{Subpath: "./internal", Blocked: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListExports() = %+v, want %+v", got, want)
+	}
+}
+
+func TestExportsMatrix(t *testing.T) {
+	r := NewSubpathResolver(SubpathResolverConfig{
+		Exports: map[string]any{
+			".": map[string]any{
+				"types": "./index.d.ts",
+				"node": map[string]any{
+					"import":  "./node.mjs",
+					"require": "./node.cjs",
+				},
+				"browser": []any{"invalid.js", "./browser.js", "./unreachable.js"},
+				"default": "./index.js",
+			},
+			"./features/*": map[string]any{
+				"development": "./src/*.ts",
+				"production":  nil,
+			},
+		},
+		Imports: map[string]any{
+			"#dep": map[string]any{"node": "dep-native", "default": "./polyfill.js"},
+		},
+	})
+
+	tests := []struct {
+		name string
+		got  []ConditionTarget
+		want []ConditionTarget
+	}{
+		{
+			name: "conditions",
+			got:  r.ExportsMatrix("."),
+			want: []ConditionTarget{
+				{Conditions: []string{"browser"}, Target: "./browser.js"},
+				{Conditions: []string{"node", "import"}, Target: "./node.mjs"},
+				{Conditions: []string{"node", "require"}, Target: "./node.cjs"},
+				{Conditions: []string{"types"}, Target: "./index.d.ts"},
+				{Conditions: []string{"default"}, Target: "./index.js"},
+			},
+		},
+		{
+			name: "pattern",
+			got:  r.ExportsMatrix("./features/a"),
+			want: []ConditionTarget{
+				{Conditions: []string{"development"}, Target: "./src/a.ts"},
+				{Conditions: []string{"production"}, Blocked: true},
+			},
+		},
+		{
+			name: "imports",
+			got:  r.ImportsMatrix("#dep"),
+			want: []ConditionTarget{
+				{Conditions: []string{"node"}, Target: "dep-native"},
+				{Conditions: []string{"default"}, Target: "./polyfill.js"},
+			},
+		},
+		{
+			name: "missing",
+			got:  r.ExportsMatrix("./missing"),
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("got %+v, want %+v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportsMatrixOrder(t *testing.T) {
+	manifest, err := ParseJSON([]byte(`{
+		"exports": {"default": "./a.js", "import": "./b.js"},
+		"imports": {"#dep": {"node": {"require": "./node.cjs", "default": "./node.js", "import": "./node.mjs"}, "default": "./dep.js"}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewSubpathResolver(SubpathResolverConfig{
+		Exports: manifest.Get("exports"),
+		Imports: manifest.Get("imports"),
+	})
+
+	tests := []struct {
+		name string
+		got  []ConditionTarget
+		want []ConditionTarget
+	}{
+		{
+			name: "default first",
+			got:  r.ExportsMatrix("."),
+			want: []ConditionTarget{
+				{Conditions: []string{"default"}, Target: "./a.js"},
+			},
+		},
+		{
+			name: "nested default",
+			got:  r.ImportsMatrix("#dep"),
+			want: []ConditionTarget{
+				{Conditions: []string{"node", "require"}, Target: "./node.cjs"},
+				{Conditions: []string{"node", "default"}, Target: "./node.js"},
+				{Conditions: []string{"default"}, Target: "./dep.js"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("got %+v, want %+v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportsMatrixMatchesResolution(t *testing.T) {
+	tests := []struct {
+		exports    string
+		conditions []string
+		want       string
+	}{
+		{`{"default": "./a.js", "import": "./b.js"}`, []string{"import", "default"}, "./a.js"},
+		{`{"import": "./b.mjs", "require": "./b.cjs"}`, []string{"require", "import", "default"}, "./b.mjs"},
+		{`{"node": {"require": "./n.cjs", "import": "./n.mjs"}, "default": "./d.js"}`, []string{"import", "node", "default"}, "./n.mjs"},
+		{`{"browser": null, "default": "./d.js"}`, []string{"browser", "default"}, ""},
+	}
+
+	// firstActive returns the target of the first matrix entry whose conditions are
+	// all active, the one resolution picks.
+	firstActive := func(matrix []ConditionTarget, conditions []string) string {
+		for _, entry := range matrix {
+			if !slices.ContainsFunc(entry.Conditions, func(c string) bool { return !slices.Contains(conditions, c) }) {
+				return entry.Target
+			}
+		}
+		return ""
+	}
+
+	for _, tt := range tests {
+		exports, err := ParseJSON([]byte(tt.exports))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range []any{exports, exports.Raw()} {
+			r := NewSubpathResolver(SubpathResolverConfig{Exports: field, Conditions: tt.conditions})
+			var resolved string
+			if targets := r.ResolveExports("."); len(targets) > 0 {
+				resolved = targets[0]
+			}
+			if matrix := firstActive(r.ExportsMatrix("."), tt.conditions); resolved != matrix {
+				t.Errorf("%s (%T) under %v: ResolveExports() = %q, ExportsMatrix() picks %q", tt.exports, field, tt.conditions, resolved, matrix)
+			}
+			if _, ordered := field.(*JSONValue); ordered && resolved != tt.want {
+				t.Errorf("%s under %v: ResolveExports() = %q, want %q", tt.exports, tt.conditions, resolved, tt.want)
+			}
+		}
+	}
+}